@@ -0,0 +1,4 @@
+// Package devicesv1 holds the generated gRPC API of the devices service.
+package devicesv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative devices/v1/devices.proto
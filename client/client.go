@@ -0,0 +1,333 @@
+// Package client is a Go client for the devices HTTP API.
+//
+//	c, err := client.New("http://localhost:8080", client.WithAPIKey(key))
+//	...
+//	it := c.List(ctx, 100)
+//	for it.Next() {
+//		fmt.Println(it.Device().Name)
+//	}
+//	if err := it.Err(); err != nil { ... }
+//
+// Requests answered with 429 Too Many Requests are retried after the delay
+// the server asks for; idempotent requests are also retried on 5xx responses
+// and network errors, with exponential backoff.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+)
+
+// Device is a device as stored by the service.
+type Device = device.Device
+
+// SearchResult is a device matching a full-text search, with its relevance
+// score and the matching words of its name and brand marked up.
+type SearchResult = device.SearchResult
+
+const (
+	// devicesPath is where the API version the client speaks serves devices.
+	devicesPath = "/v1/devices"
+
+	defaultMaxRetries = 3
+	defaultBaseDelay  = 100 * time.Millisecond
+	defaultMaxDelay   = 5 * time.Second
+)
+
+// Client calls the devices API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+
+	// sleep waits between attempts; replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends requests through hc instead of http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithAPIKey authenticates requests with key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithRetries retries failed requests up to maxRetries times, waiting
+// baseDelay before the first retry and doubling it up to maxDelay after each.
+// Zero maxRetries disables retries.
+func WithRetries(maxRetries int, baseDelay, maxDelay time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.baseDelay = baseDelay
+		c.maxDelay = maxDelay
+	}
+}
+
+// New returns a client for the service at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("base URL %q must be http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		maxRetries: defaultMaxRetries,
+		baseDelay:  defaultBaseDelay,
+		maxDelay:   defaultMaxDelay,
+		sleep:      sleep,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Get returns the device with the given id, or an error matching ErrNotFound.
+func (c *Client) Get(ctx context.Context, id string) (*Device, error) {
+	if id == "" {
+		return nil, ErrMissingID
+	}
+
+	var resp struct {
+		Device Device `json:"device"`
+	}
+	if err := c.do(ctx, http.MethodGet, devicesPath+"/"+url.PathEscape(id), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Device, nil
+}
+
+// Page is one page of a listing.
+type Page struct {
+	Devices []Device
+	// NextPageToken fetches the following page; empty on the last one.
+	NextPageToken string
+}
+
+// ListPage returns up to pageSize devices, ordered by creation time, starting
+// at the page pageToken points to; the empty token starts from the beginning.
+func (c *Client) ListPage(ctx context.Context, pageSize int, pageToken string) (Page, error) {
+	query := url.Values{"pageSize": {strconv.Itoa(pageSize)}}
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+
+	var resp struct {
+		Devices       []Device `json:"devices"`
+		NextPageToken string   `json:"nextPageToken"`
+	}
+	err := c.do(ctx, http.MethodGet, devicesPath, query, nil, &resp)
+	if errors.Is(err, ErrNotFound) {
+		// The service answers an empty listing with 404.
+		return Page{}, nil
+	} else if err != nil {
+		return Page{}, err
+	}
+	return Page{Devices: resp.Devices, NextPageToken: resp.NextPageToken}, nil
+}
+
+// List iterates over every device, fetching pageSize devices at a time.
+func (c *Client) List(ctx context.Context, pageSize int) *Iterator {
+	return &Iterator{ctx: ctx, client: c, pageSize: pageSize}
+}
+
+// Search returns the devices of a brand.
+func (c *Client) Search(ctx context.Context, brand string) ([]Device, error) {
+	var resp struct {
+		Devices []Device `json:"devices"`
+	}
+	err := c.do(ctx, http.MethodGet, devicesPath+"/search", url.Values{"brand": {brand}}, nil, &resp)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return resp.Devices, nil
+}
+
+// SearchText returns the devices whose name or brand match query, best
+// first: every word matches as a prefix, misspelled ones approximately. It
+// returns at most limit results, or the server's default of 20 when limit is
+// 0; the server allows up to 100.
+func (c *Client) SearchText(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	params := url.Values{"q": {query}}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp struct {
+		Results []SearchResult `json:"results"`
+	}
+	err := c.do(ctx, http.MethodGet, devicesPath+"/search", params, nil, &resp)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Create stores a new device with the name and brand of dvc and returns it
+// with its id and timestamps. It is not retried on server errors, since the
+// device may have been stored.
+func (c *Client) Create(ctx context.Context, dvc Device) (*Device, error) {
+	var resp struct {
+		Device Device `json:"device"`
+	}
+	body := Device{Name: dvc.Name, Brand: dvc.Brand}
+	if err := c.do(ctx, http.MethodPost, devicesPath, nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Device, nil
+}
+
+// Update changes the non-empty name and brand of the device with the given id.
+func (c *Client) Update(ctx context.Context, id string, changes Device) error {
+	if id == "" {
+		return ErrMissingID
+	}
+
+	body := Device{Name: changes.Name, Brand: changes.Brand}
+	return c.do(ctx, http.MethodPatch, devicesPath+"/"+url.PathEscape(id), nil, body, nil)
+}
+
+// Delete removes the device with the given id.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrMissingID
+	}
+
+	return c.do(ctx, http.MethodDelete, devicesPath+"/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// do sends a request, retrying it as the package documentation describes,
+// and decodes a successful response into out when it is not nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u.String(), body)
+		if err == nil && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out == nil {
+				return nil
+			}
+			return json.NewDecoder(resp.Body).Decode(out)
+		}
+
+		var retryAfter time.Duration
+		if err == nil {
+			apiErr := newAPIError(resp)
+			err, retryAfter = apiErr, apiErr.RetryAfter
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt >= c.maxRetries || !retryable(method, err) {
+			return err
+		}
+
+		delay := c.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if err := c.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, target string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// retryable reports whether a failed request may be sent again: rate-limited
+// requests were not processed, and idempotent ones can be repeated safely.
+func retryable(method string, err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			return true
+		}
+		if apiErr.StatusCode < 500 {
+			return false
+		}
+	}
+	return method != http.MethodPost
+}
+
+// backoff returns the delay before retry attempt+1: exponential, capped at
+// maxDelay, with full jitter over its upper half.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.baseDelay << attempt
+	if delay <= 0 || delay > c.maxDelay {
+		delay = c.maxDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drain reads what is left of a response body so the connection can be reused.
+func drain(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, 64<<10))
+	body.Close()
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/app"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"go.uber.org/zap"
+)
+
+const testAPIKey = "secret"
+
+// newRouter returns the service router over an in-memory repository.
+func newRouter(t *testing.T, configure func(*config.Config)) http.Handler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.Auth.APIKeys = []string{"test:" + testAPIKey}
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	return app.NewRouter(cfg, app.Options{
+		Logger:   zap.NewNop(),
+		Devices:  &device.MockRepository{},
+		Registry: prometheus.NewRegistry(),
+	})
+}
+
+// newClient returns a client for handler that records its retry delays
+// instead of sleeping.
+func newClient(t *testing.T, handler http.Handler, opts ...Option) (*Client, *[]time.Duration) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := New(srv.URL, append([]Option{WithAPIKey(testAPIKey)}, opts...)...)
+	require.NoError(t, err)
+
+	var delays []time.Duration
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return c, &delays
+}
+
+func TestClient_CRUD(t *testing.T) {
+	c, _ := newClient(t, newRouter(t, nil))
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, Device{Name: "Pixel 8", Brand: "Google"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.False(t, created.CreationTime.IsZero())
+
+	got, err := c.Get(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, *created, *got)
+
+	require.NoError(t, c.Update(ctx, created.ID, Device{Name: "Pixel 8 Pro"}))
+	got, err = c.Get(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Pixel 8 Pro", got.Name)
+	assert.Equal(t, "Google", got.Brand)
+
+	found, err := c.Search(ctx, "Google")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, created.ID, found[0].ID)
+
+	found, err = c.Search(ctx, "Nokia")
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	results, err := c.SearchText(ctx, "pixl", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, created.ID, results[0].Device.ID)
+	assert.Positive(t, results[0].Score)
+
+	results, err = c.SearchText(ctx, "nokia", 0)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	require.NoError(t, c.Delete(ctx, created.ID))
+	_, err = c.Get(ctx, created.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, c.Delete(ctx, created.ID), ErrNotFound)
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	c, _ := newClient(t, newRouter(t, nil))
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, Device{Name: "Pixel 8", Brand: "Google"})
+	require.NoError(t, err)
+
+	err = c.Update(ctx, created.ID, Device{})
+	assert.ErrorIs(t, err, ErrInvalidUpdate)
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+
+	_, err = c.ListPage(ctx, 10, "bogus!")
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+
+	unauthenticated, _ := newClient(t, newRouter(t, nil), WithAPIKey("wrong"))
+	_, err = unauthenticated.Get(ctx, created.ID)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestClient_ListIteratesPages(t *testing.T) {
+	c, _ := newClient(t, newRouter(t, nil))
+	ctx := context.Background()
+
+	it := c.List(ctx, 2)
+	assert.False(t, it.Next(), "empty listing")
+	require.NoError(t, it.Err())
+
+	var created []string
+	for _, name := range []string{"Pixel 7", "Pixel 8", "iPhone 15", "Galaxy S24", "iPhone 14"} {
+		dvc, err := c.Create(ctx, Device{Name: name, Brand: "Any"})
+		require.NoError(t, err)
+		created = append(created, dvc.ID)
+		time.Sleep(time.Millisecond)
+	}
+
+	var listed []string
+	it = c.List(ctx, 2)
+	for it.Next() {
+		listed = append(listed, it.Device().ID)
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, created, listed)
+}
+
+func TestClient_RetriesServerErrors(t *testing.T) {
+	router := newRouter(t, nil)
+	var failures atomic.Int32
+	failures.Store(2)
+	flaky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures.Add(-1) >= 0 {
+			http.Error(w, `{"error":"unavailable"}`, http.StatusServiceUnavailable)
+			return
+		}
+		router.ServeHTTP(w, r)
+	})
+	c, delays := newClient(t, flaky, WithRetries(3, 100*time.Millisecond, time.Second))
+
+	_, err := c.Search(context.Background(), "Google")
+	require.NoError(t, err)
+	require.Len(t, *delays, 2)
+	assert.GreaterOrEqual(t, (*delays)[0], 50*time.Millisecond)
+	assert.LessOrEqual(t, (*delays)[0], 100*time.Millisecond)
+	assert.GreaterOrEqual(t, (*delays)[1], 100*time.Millisecond)
+	assert.LessOrEqual(t, (*delays)[1], 200*time.Millisecond)
+}
+
+func TestClient_DoesNotRetryCreateOnServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	c, _ := newClient(t, failing)
+
+	_, err := c.Create(context.Background(), Device{Name: "Pixel 8", Brand: "Google"})
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_RetriesRateLimitedRequestsAfterRetryAfter(t *testing.T) {
+	router := newRouter(t, func(cfg *config.Config) {
+		cfg.RateLimit.Requests = 1
+		cfg.RateLimit.Period = time.Minute
+	})
+	c, delays := newClient(t, router, WithRetries(2, time.Millisecond, time.Millisecond))
+	ctx := context.Background()
+
+	_, err := c.Search(ctx, "Google")
+	require.NoError(t, err)
+
+	_, err = c.Search(ctx, "Google")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	require.Len(t, *delays, 2)
+	for _, d := range *delays {
+		assert.GreaterOrEqual(t, d, 30*time.Second, "waits as long as the server asks")
+	}
+}
+
+func TestNew_RejectsInvalidBaseURL(t *testing.T) {
+	_, err := New("localhost:8080")
+	assert.Error(t, err)
+}
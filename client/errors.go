@@ -0,0 +1,84 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+)
+
+var (
+	// ErrNotFound matches errors for devices that do not exist.
+	ErrNotFound = device.ErrNotFound
+	// ErrMissingID matches errors for requests that need a device id and have none.
+	ErrMissingID = device.ErrMissingID
+	// ErrInvalidUpdate matches errors for updates without fields to change.
+	ErrInvalidUpdate = device.ErrInvalidUpdate
+	// ErrInvalidPageToken matches errors for page tokens the service did not issue.
+	ErrInvalidPageToken = device.ErrInvalidPageToken
+	// ErrUnauthorized matches errors for requests with a missing or invalid API key.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited matches errors for requests rejected by the rate limiter.
+	ErrRateLimited = errors.New("rate limit exceeded")
+)
+
+// APIError is an error response from the service. Use errors.Is with the
+// Err* variables to tell failures apart.
+type APIError struct {
+	StatusCode int
+	// Message is the error reported by the service, or the status text.
+	Message string
+	// RetryAfter is how long the service asked to wait before retrying.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("devices API: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is matches the Err* variables by status code and, for bad requests, by
+// the message the service reports.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrMissingID, ErrInvalidUpdate, ErrInvalidPageToken:
+		return e.StatusCode == http.StatusBadRequest && e.Message == target.Error()
+	}
+	return false
+}
+
+// newAPIError reads an error response, whose body is {"error": message} or
+// {"status": text}, and closes it.
+func newAPIError(resp *http.Response) *APIError {
+	defer drain(resp.Body)
+
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	var body struct {
+		Error  string `json:"error"`
+		Status string `json:"status"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+	switch {
+	case body.Error != "":
+		apiErr.Message = body.Error
+	case body.Status != "":
+		apiErr.Message = body.Status
+	default:
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
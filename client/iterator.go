@@ -0,0 +1,48 @@
+package client
+
+import "context"
+
+// Iterator walks a listing page by page. Call Next until it returns false,
+// then check Err.
+type Iterator struct {
+	ctx      context.Context
+	client   *Client
+	pageSize int
+
+	page    []Device
+	current Device
+	token   string
+	started bool
+	err     error
+}
+
+// Next advances to the next device, fetching the next page when needed. It
+// returns false at the end of the listing or on error.
+func (it *Iterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || (it.started && it.token == "") {
+			return false
+		}
+
+		page, err := it.client.ListPage(it.ctx, it.pageSize, it.token)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.started = true
+		it.page, it.token = page.Devices, page.NextPageToken
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Device returns the device Next advanced to.
+func (it *Iterator) Device() Device {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
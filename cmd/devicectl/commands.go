@@ -0,0 +1,406 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/victorspringer/1g-take-home-task/client"
+)
+
+// listPageSize is how many devices list and export fetch per request.
+const listPageSize = 500
+
+func (c *cli) listCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List every device",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			devices, err := c.listAll(cmd)
+			if err != nil {
+				return err
+			}
+			return printDevices(c.out, c.output, devices)
+		},
+	}
+}
+
+func (c *cli) getCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "get ID...",
+		Short:             "Show devices by id",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: c.completeIDs,
+		RunE: func(cmd *cobra.Command, ids []string) error {
+			cl, err := c.client()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := c.context(cmd)
+			defer cancel()
+
+			var devices []client.Device
+			for _, id := range ids {
+				dvc, err := cl.Get(ctx, id)
+				if err != nil {
+					return fmt.Errorf("%s: %w", id, err)
+				}
+				devices = append(devices, *dvc)
+			}
+			return printDevices(c.out, c.output, devices)
+		},
+	}
+}
+
+func (c *cli) searchCommand() *cobra.Command {
+	var (
+		query string
+		limit int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "search BRAND | search --query TEXT [--limit N]",
+		Short: "List the devices of a brand, or those matching a text search",
+		Long: `List the devices of a brand or, with --query, the devices whose name or
+brand match a text, best first. Every word of the text matches as a prefix,
+and misspelled words approximately.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if (query == "") == (len(args) == 0) {
+				return errors.New("pass either a brand or --query")
+			}
+			return cobra.MaximumNArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := c.client()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := c.context(cmd)
+			defer cancel()
+
+			if query == "" {
+				devices, err := cl.Search(ctx, args[0])
+				if err != nil {
+					return err
+				}
+				return printDevices(c.out, c.output, devices)
+			}
+
+			results, err := cl.SearchText(ctx, query, limit)
+			if err != nil {
+				return err
+			}
+			devices := make([]client.Device, len(results))
+			for i, result := range results {
+				devices[i] = result.Device
+			}
+			return printDevices(c.out, c.output, devices)
+		},
+	}
+	cmd.Flags().StringVarP(&query, "query", "q", "", "text to search names and brands for")
+	cmd.Flags().IntVar(&limit, "limit", 0, "most devices to return with --query (default 20, at most 100)")
+	return cmd
+}
+
+func (c *cli) createCommand() *cobra.Command {
+	var dvc client.Device
+
+	cmd := &cobra.Command{
+		Use:   "create --name NAME --brand BRAND",
+		Short: "Create a device",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cl, err := c.client()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := c.context(cmd)
+			defer cancel()
+
+			created, err := cl.Create(ctx, dvc)
+			if err != nil {
+				return err
+			}
+			return printDevices(c.out, c.output, []client.Device{*created})
+		},
+	}
+	cmd.Flags().StringVar(&dvc.Name, "name", "", "device name")
+	cmd.Flags().StringVar(&dvc.Brand, "brand", "", "device brand")
+	_ = cmd.MarkFlagRequired("name")
+	_ = cmd.MarkFlagRequired("brand")
+	return cmd
+}
+
+func (c *cli) updateCommand() *cobra.Command {
+	var changes client.Device
+
+	cmd := &cobra.Command{
+		Use:               "update ID [--name NAME] [--brand BRAND]",
+		Short:             "Change the name or brand of a device",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: c.completeIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if changes.Name == "" && changes.Brand == "" {
+				return errors.New("nothing to update: pass --name or --brand")
+			}
+			cl, err := c.client()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := c.context(cmd)
+			defer cancel()
+
+			if err := cl.Update(ctx, args[0], changes); err != nil {
+				return err
+			}
+			updated, err := cl.Get(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			return printDevices(c.out, c.output, []client.Device{*updated})
+		},
+	}
+	cmd.Flags().StringVar(&changes.Name, "name", "", "new device name")
+	cmd.Flags().StringVar(&changes.Brand, "brand", "", "new device brand")
+	return cmd
+}
+
+func (c *cli) deleteCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:               "delete ID...",
+		Short:             "Delete devices by id",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: c.completeIDs,
+		RunE: func(cmd *cobra.Command, ids []string) error {
+			cl, err := c.client()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := c.context(cmd)
+			defer cancel()
+
+			for _, id := range ids {
+				if err := cl.Delete(ctx, id); err != nil {
+					return fmt.Errorf("%s: %w", id, err)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "deleted %s\n", id)
+			}
+			return nil
+		},
+	}
+}
+
+func (c *cli) importCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import FILE",
+		Short: "Create the devices listed in a JSON or YAML file (- for stdin)",
+		Long: `Create the devices listed in a JSON or YAML file, such as one written by
+export. Only names and brands are read: the service assigns new ids and
+timestamps. Import stops at the first device that fails. --timeout applies
+to each device rather than to the whole import.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+
+			records, err := readImport(in)
+			if err != nil {
+				return err
+			}
+
+			cl, err := c.client()
+			if err != nil {
+				return err
+			}
+			created := make([]client.Device, 0, len(records))
+			for i, r := range records {
+				ctx, cancel := c.context(cmd)
+				dvc, err := cl.Create(ctx, client.Device{Name: r.Name, Brand: r.Brand})
+				cancel()
+				if err != nil {
+					return fmt.Errorf("device %d (%s): %w; %d of %d imported", i+1, r.Name, err, len(created), len(records))
+				}
+				created = append(created, *dvc)
+			}
+			return printDevices(c.out, c.output, created)
+		},
+	}
+}
+
+func (c *cli) exportCommand() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "export [FILE]",
+		Short: "Write every device to a JSON or YAML file, or stdout",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "json" && format != "yaml" {
+				return fmt.Errorf("unknown export format %q, expected json or yaml", format)
+			}
+
+			devices, err := c.listAll(cmd)
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 || args[0] == "-" {
+				return printDevices(c.out, format, devices)
+			}
+
+			f, err := os.Create(args[0])
+			if err != nil {
+				return err
+			}
+			if err := printDevices(f, format, devices); err != nil {
+				f.Close()
+				return err
+			}
+			// Writes may only fail once the file is closed.
+			return f.Close()
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "json", "file format: json or yaml")
+	_ = cmd.RegisterFlagCompletionFunc("format", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json", "yaml"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	return cmd
+}
+
+// listAll fetches every device, page by page.
+func (c *cli) listAll(cmd *cobra.Command) ([]client.Device, error) {
+	cl, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.context(cmd)
+	defer cancel()
+
+	var devices []client.Device
+	it := cl.List(ctx, listPageSize)
+	for it.Next() {
+		devices = append(devices, it.Device())
+	}
+	return devices, it.Err()
+}
+
+// completeIDs completes device ids from the server, with their names as descriptions.
+func (c *cli) completeIDs(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	devices, err := c.listAll(cmd)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveError
+	}
+
+	ids := make([]string, len(devices))
+	for i, dvc := range devices {
+		ids[i] = dvc.ID + "\t" + dvc.Name
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+func (c *cli) profileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage the servers and credentials devicectl uses",
+	}
+
+	completeNames := func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		p, _ := loadProfiles(c.configPath)
+		return p.names(), cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var settings profile
+	set := &cobra.Command{
+		Use:   "set NAME --server URL [--api-key KEY]",
+		Short: "Add or change a profile; the first one becomes current",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := loadProfiles(c.configPath)
+			if err != nil {
+				return err
+			}
+			if p.Profiles == nil {
+				p.Profiles = make(map[string]profile)
+			}
+			p.Profiles[args[0]] = settings
+			if p.Current == "" {
+				p.Current = args[0]
+			}
+			return p.save(c.configPath)
+		},
+	}
+	set.Flags().StringVar(&settings.Server, "server", "", "base URL of the service")
+	set.Flags().StringVar(&settings.APIKey, "api-key", "", "API key")
+	_ = set.MarkFlagRequired("server")
+
+	use := &cobra.Command{
+		Use:               "use NAME",
+		Short:             "Make a profile current",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := loadProfiles(c.configPath)
+			if err != nil {
+				return err
+			}
+			if _, found := p.Profiles[args[0]]; !found {
+				return fmt.Errorf("unknown profile %q", args[0])
+			}
+			p.Current = args[0]
+			return p.save(c.configPath)
+		},
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List profiles; the current one is marked with *",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			p, err := loadProfiles(c.configPath)
+			if err != nil {
+				return err
+			}
+			for _, name := range p.names() {
+				marker := " "
+				if name == p.Current {
+					marker = "*"
+				}
+				fmt.Fprintf(c.out, "%s %s\t%s\n", marker, name, p.Profiles[name].Server)
+			}
+			return nil
+		},
+	}
+
+	remove := &cobra.Command{
+		Use:               "delete NAME",
+		Short:             "Delete a profile",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeNames,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := loadProfiles(c.configPath)
+			if err != nil {
+				return err
+			}
+			if _, found := p.Profiles[args[0]]; !found {
+				return fmt.Errorf("unknown profile %q", args[0])
+			}
+			delete(p.Profiles, args[0])
+			if p.Current == args[0] {
+				p.Current = ""
+			}
+			return p.save(c.configPath)
+		},
+	}
+
+	cmd.AddCommand(set, use, list, remove)
+	return cmd
+}
@@ -0,0 +1,128 @@
+// Command devicectl manages devices through the devices API.
+//
+// The server and API key come from --server and --api-key, the
+// DEVICECTL_SERVER and DEVICECTL_API_KEY environment variables, or a profile
+// in the configuration file, in that order of precedence.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/victorspringer/1g-take-home-task/client"
+)
+
+func main() {
+	if err := newRootCommand(os.Stdout, os.Stderr, os.Getenv).Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// cli holds the global flags and how commands reach the outside world.
+type cli struct {
+	out    io.Writer
+	getenv func(string) string
+
+	configPath string
+	profile    string
+	server     string
+	apiKey     string
+	output     string
+	timeout    time.Duration
+}
+
+func newRootCommand(out, errOut io.Writer, getenv func(string) string) *cobra.Command {
+	c := &cli{out: out, getenv: getenv}
+
+	root := &cobra.Command{
+		Use:          "devicectl",
+		Short:        "Manage devices through the devices API",
+		SilenceUsage: true,
+	}
+	root.SetOut(out)
+	root.SetErr(errOut)
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&c.configPath, "config", defaultConfigPath(), "profiles file")
+	flags.StringVarP(&c.profile, "profile", "p", "", "profile to use instead of the current one (env DEVICECTL_PROFILE)")
+	flags.StringVar(&c.server, "server", "", "base URL of the service, e.g. http://localhost:8080 (env DEVICECTL_SERVER)")
+	flags.StringVar(&c.apiKey, "api-key", "", "API key (env DEVICECTL_API_KEY)")
+	flags.StringVarP(&c.output, "output", "o", "table", "output format: table, json or yaml")
+	flags.DurationVar(&c.timeout, "timeout", 30*time.Second, "time allowed for each command, or for each device of an import")
+
+	_ = root.RegisterFlagCompletionFunc("output", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return outputFormats, cobra.ShellCompDirectiveNoFileComp
+	})
+	_ = root.RegisterFlagCompletionFunc("profile", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		p, _ := loadProfiles(c.configPath)
+		return p.names(), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	root.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error {
+		if !slices.Contains(outputFormats, c.output) {
+			return fmt.Errorf("unknown output format %q, expected table, json or yaml", c.output)
+		}
+		return nil
+	}
+
+	root.AddCommand(
+		c.listCommand(),
+		c.getCommand(),
+		c.searchCommand(),
+		c.createCommand(),
+		c.updateCommand(),
+		c.deleteCommand(),
+		c.importCommand(),
+		c.exportCommand(),
+		c.profileCommand(),
+	)
+	return root
+}
+
+// client returns a client for the selected server: flags win over the
+// environment, which wins over the profile.
+func (c *cli) client() (*client.Client, error) {
+	name := c.profile
+	if name == "" {
+		name = c.getenv("DEVICECTL_PROFILE")
+	}
+	p, err := loadProfiles(c.configPath)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := p.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	server := firstNonEmpty(c.server, c.getenv("DEVICECTL_SERVER"), selected.Server)
+	if server == "" {
+		return nil, fmt.Errorf("no server configured: pass --server, set DEVICECTL_SERVER or add a profile")
+	}
+	apiKey := firstNonEmpty(c.apiKey, c.getenv("DEVICECTL_API_KEY"), selected.APIKey)
+
+	return client.New(server,
+		client.WithAPIKey(apiKey),
+		client.WithHTTPClient(&http.Client{Timeout: c.timeout}),
+	)
+}
+
+// context bounds a command by --timeout.
+func (c *cli) context(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(cmd.Context(), c.timeout)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/app"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"go.uber.org/zap"
+)
+
+// newServer starts the service over an in-memory repository and returns its
+// URL and API key.
+func newServer(t *testing.T) (string, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.Auth.APIKeys = []string{"test:secret"}
+	srv := httptest.NewServer(app.NewRouter(cfg, app.Options{
+		Logger:   zap.NewNop(),
+		Devices:  &device.MockRepository{},
+		Registry: prometheus.NewRegistry(),
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, "secret"
+}
+
+// run executes devicectl with args and environment env, returning stdout.
+func run(t *testing.T, env map[string]string, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	root := newRootCommand(&out, &errOut, func(key string) string { return env[key] })
+	root.SetIn(strings.NewReader(stdin))
+	root.SetArgs(args)
+	err := root.Execute()
+	return out.String(), err
+}
+
+func TestDevicectl_ImportListExport(t *testing.T) {
+	url, key := newServer(t)
+	env := map[string]string{"DEVICECTL_SERVER": url, "DEVICECTL_API_KEY": key}
+	config := filepath.Join(t.TempDir(), "config.yaml")
+
+	out, err := run(t, env, "- name: Pixel 8\n  brand: Google\n- name: iPhone 15\n  brand: Apple\n",
+		"--config", config, "import", "-", "-o", "json")
+	require.NoError(t, err)
+	var imported []record
+	require.NoError(t, json.Unmarshal([]byte(out), &imported))
+	require.Len(t, imported, 2)
+	assert.NotEmpty(t, imported[0].ID)
+
+	out, err = run(t, env, "", "--config", config, "list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "NAME")
+	assert.Contains(t, out, "Pixel 8")
+	assert.Contains(t, out, "iPhone 15")
+
+	exported := filepath.Join(t.TempDir(), "devices.yaml")
+	_, err = run(t, env, "", "--config", config, "export", exported, "--format", "yaml")
+	require.NoError(t, err)
+	data, err := os.ReadFile(exported)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "brand: Apple")
+
+	out, err = run(t, env, "", "--config", config, "search", "Apple", "-o", "yaml")
+	require.NoError(t, err)
+	assert.Contains(t, out, "iPhone 15")
+	assert.NotContains(t, out, "Pixel 8")
+
+	out, err = run(t, env, "", "--config", config, "search", "--query", "pixle")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Pixel 8")
+	assert.NotContains(t, out, "iPhone 15")
+
+	_, err = run(t, env, "", "--config", config, "search", "Apple", "--query", "pixel")
+	assert.EqualError(t, err, "pass either a brand or --query")
+
+	out, err = run(t, env, "", "--config", config, "update", imported[0].ID, "--name", "Pixel 8 Pro")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Pixel 8 Pro")
+
+	_, err = run(t, env, "", "--config", config, "delete", imported[0].ID)
+	require.NoError(t, err)
+	_, err = run(t, env, "", "--config", config, "get", imported[0].ID)
+	assert.Error(t, err)
+}
+
+func TestDevicectl_ImportTimesOutPerDevice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	router := app.NewRouter(cfg, app.Options{Logger: zap.NewNop(), Devices: &device.MockRepository{}, Registry: prometheus.NewRegistry()})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(40 * time.Millisecond)
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	env := map[string]string{"DEVICECTL_SERVER": srv.URL}
+
+	// Each device takes most of the timeout, all of them much more.
+	var stdin strings.Builder
+	for i := 0; i < 4; i++ {
+		stdin.WriteString("- name: Pixel 8\n  brand: Google\n")
+	}
+	out, err := run(t, env, stdin.String(), "--config", filepath.Join(t.TempDir(), "config.yaml"), "--timeout", "100ms", "import", "-", "-o", "json")
+	require.NoError(t, err)
+	var imported []record
+	require.NoError(t, json.Unmarshal([]byte(out), &imported))
+	assert.Len(t, imported, 4)
+}
+
+func TestDevicectl_Profiles(t *testing.T) {
+	url, key := newServer(t)
+	config := filepath.Join(t.TempDir(), "config.yaml")
+
+	_, err := run(t, nil, "", "--config", config, "list")
+	assert.ErrorContains(t, err, "no server configured")
+
+	_, err = run(t, nil, "", "--config", config, "profile", "set", "local", "--server", url, "--api-key", key)
+	require.NoError(t, err)
+	_, err = run(t, nil, "", "--config", config, "profile", "set", "broken", "--server", url, "--api-key", "wrong")
+	require.NoError(t, err)
+
+	out, err := run(t, nil, "", "--config", config, "profile", "list")
+	require.NoError(t, err)
+	assert.Contains(t, out, "* local")
+	assert.Contains(t, out, "  broken")
+
+	_, err = run(t, nil, "", "--config", config, "create", "--name", "Pixel 8", "--brand", "Google")
+	require.NoError(t, err, "uses the current profile")
+
+	_, err = run(t, nil, "", "--config", config, "-p", "broken", "list")
+	assert.Error(t, err, "--profile overrides the current one")
+
+	_, err = run(t, map[string]string{"DEVICECTL_API_KEY": key}, "", "--config", config, "-p", "broken", "list")
+	assert.NoError(t, err, "the environment overrides the profile")
+
+	_, err = run(t, nil, "", "--config", config, "profile", "use", "missing")
+	assert.ErrorContains(t, err, "unknown profile")
+
+	info, err := os.Stat(config)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestDevicectl_RejectsUnknownOutputFormat(t *testing.T) {
+	_, err := run(t, nil, "", "--config", filepath.Join(t.TempDir(), "config.yaml"), "list", "-o", "xml")
+	assert.ErrorContains(t, err, "unknown output format")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/victorspringer/1g-take-home-task/client"
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormats are the values accepted by --output.
+var outputFormats = []string{"table", "json", "yaml"}
+
+// record is a device as devicectl prints, exports and imports it, with the
+// field names of the API in every format.
+type record struct {
+	ID           string    `json:"id,omitempty" yaml:"id,omitempty"`
+	Name         string    `json:"name" yaml:"name"`
+	Brand        string    `json:"brand" yaml:"brand"`
+	CreationTime time.Time `json:"creationTime" yaml:"creationTime,omitempty"`
+	UpdateTime   time.Time `json:"updateTime" yaml:"updateTime,omitempty"`
+}
+
+func toRecords(devices []client.Device) []record {
+	records := make([]record, len(devices))
+	for i, dvc := range devices {
+		records[i] = record(dvc)
+	}
+	return records
+}
+
+// printDevices writes devices in format. JSON and YAML output is a list even
+// for a single device, so scripts handle every command the same way.
+func printDevices(w io.Writer, format string, devices []client.Device) error {
+	records := toRecords(devices)
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(records); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tBRAND\tCREATED\tUPDATED")
+		for _, r := range records {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.ID, r.Name, r.Brand, r.CreationTime.Format(time.RFC3339), r.UpdateTime.Format(time.RFC3339))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q, expected table, json or yaml", format)
+	}
+}
+
+// importRecord is the part of an exported device that import uses: the
+// service assigns new ids and timestamps.
+type importRecord struct {
+	Name  string `yaml:"name"`
+	Brand string `yaml:"brand"`
+}
+
+// readImport decodes a JSON or YAML list of devices; JSON is valid YAML.
+func readImport(r io.Reader) ([]importRecord, error) {
+	var records []importRecord
+	if err := yaml.NewDecoder(r).Decode(&records); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("decoding devices: %w", err)
+	}
+	return records, nil
+}
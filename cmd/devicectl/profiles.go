@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profiles is the devicectl configuration file: named servers with their
+// credentials, and the one used by default.
+//
+//	current: prod
+//	profiles:
+//	  local:
+//	    server: http://localhost:8080
+//	  prod:
+//	    server: https://devices.example.com
+//	    apiKey: ...
+type profiles struct {
+	Current  string             `yaml:"current,omitempty"`
+	Profiles map[string]profile `yaml:"profiles,omitempty"`
+}
+
+type profile struct {
+	Server string `yaml:"server"`
+	APIKey string `yaml:"apiKey,omitempty"`
+}
+
+// defaultConfigPath is devicectl/config.yaml in the user configuration directory.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "devicectl", "config.yaml")
+}
+
+// loadProfiles reads the profiles at path; a missing file has none.
+func loadProfiles(path string) (profiles, error) {
+	var p profiles
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	} else if err != nil {
+		return p, err
+	}
+
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// save writes the profiles to path, readable only by the user since they
+// hold API keys.
+func (p profiles) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// names returns the profile names, sorted.
+func (p profiles) names() []string {
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolve picks the profile named name, or the current one when name is
+// empty. With neither, it returns the zero profile.
+func (p profiles) resolve(name string) (profile, error) {
+	if name == "" {
+		name = p.Current
+	}
+	if name == "" {
+		return profile{}, nil
+	}
+
+	selected, found := p.Profiles[name]
+	if !found {
+		return profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+	return selected, nil
+}
@@ -0,0 +1,634 @@
+// Package v2 Code generated by swaggo/swag. DO NOT EDIT
+package v2
+
+import "github.com/swaggo/swag"
+
+const docTemplatev2 = `{
+    "schemes": {{ marshal .Schemes }},
+    "swagger": "2.0",
+    "info": {
+        "description": "{{escape .Description}}",
+        "title": "{{.Title}}",
+        "contact": {
+            "name": "Victor Springer"
+        },
+        "license": {
+            "name": "MIT License"
+        },
+        "version": "{{.Version}}"
+    },
+    "host": "{{.Host}}",
+    "basePath": "{{.BasePath}}",
+    "paths": {
+        "/devices": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a list of all devices, ordered by creation time. With pageSize the list is paginated: pass the returned nextPageToken as pageToken to get the following page.",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "summary": "List all devices",
+                "operationId": "list-all-devices",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Devices per page, up to 1000",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token of the page to get",
+                        "name": "pageToken",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated device fields to return: id, name, brand, creationTime, updateTime",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated relations to add under _expanded: brand",
+                        "name": "expand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a copy the client holds",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a new device and returns it with its id and timestamps",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "summary": "Add device",
+                "operationId": "add-device",
+                "parameters": [
+                    {
+                        "description": "Device to add",
+                        "name": "device",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/device.Device"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/devices/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a list of device data by brand, or, with q, devices whose name or brand match the words typed, best first.\nWords match as prefixes (\"galaxy s2\" finds \"Galaxy S23\") and misspelled ones by similarity; highlights mark the matching words with \u003cmark\u003e tags.",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "summary": "Search devices",
+                "operationId": "search-devices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device's brand",
+                        "name": "brand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Free-text query",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results with q (default 20, at most 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated device fields to return with brand: id, name, brand, creationTime, updateTime",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated relations to add under _expanded: brand",
+                        "name": "expand",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/devices/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the number of devices, in total and per brand, and a histogram of the devices created per day, week or month in [from, to).\nBuckets start at midnight UTC, weeks on Monday; every interval in the range has a bucket, empty ones included.",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "summary": "Get device statistics",
+                "operationId": "device-stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Histogram bucket width: day (default), week or month",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the histogram, RFC 3339 (default 30 intervals before to)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the histogram, RFC 3339, exclusive (default now)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/devices/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get device data by id",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "summary": "Get device by id",
+                "operationId": "get-device-by-id",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device's ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated device fields to return: id, name, brand, creationTime, updateTime",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated relations to add under _expanded: brand",
+                        "name": "expand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a copy the client holds",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified of a copy the client holds",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete device data by id",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "summary": "Delete device",
+                "operationId": "delete-device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device's ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update device data by id",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "summary": "Update device",
+                "operationId": "update-device",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Device's ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "device",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/device.Device"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/devices:batchDelete": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete up to 1000 devices in one request. Results follow the order of ids, with a \"Not Found\" status for devices that did not exist.",
+                "consumes": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "summary": "Delete devices by IDs",
+                "operationId": "batch-delete-devices",
+                "parameters": [
+                    {
+                        "description": "Device IDs",
+                        "name": "ids",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/app.batchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/devices:batchGet": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get up to 1000 devices in one request. Results follow the order of ids, with a \"Not Found\" status for missing devices.",
+                "consumes": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "summary": "Get devices by IDs",
+                "operationId": "batch-get-devices",
+                "parameters": [
+                    {
+                        "description": "Device IDs",
+                        "name": "ids",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/app.batchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/graphql": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Run a GraphQL query or mutation over devices",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GraphQL",
+                "operationId": "graphql",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "app.batchRequest": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "device.Device": {
+            "type": "object",
+            "properties": {
+                "brand": {
+                    "type": "string"
+                },
+                "creationTime": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updateTime": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        }
+    }
+}`
+
+// SwaggerInfov2 holds exported Swagger Info so clients can modify it
+var SwaggerInfov2 = &swag.Spec{
+	Version:          "2.0",
+	Host:             "localhost:8080",
+	BasePath:         "/v2",
+	Schemes:          []string{},
+	Title:            "Devices Service",
+	Description:      "Devices Service for technical challenge, version 2.\nEvery response body is an envelope: results under \"data\" (with \"nextPageToken\" beside them on paginated listings), failures as {\"error\": {\"code\": <HTTP status>, \"message\": \"...\"}}.",
+	InfoInstanceName: "v2",
+	SwaggerTemplate:  docTemplatev2,
+	LeftDelim:        "{{",
+	RightDelim:       "}}",
+}
+
+func init() {
+	swag.Register(SwaggerInfov2.InstanceName(), SwaggerInfov2)
+}
@@ -2,81 +2,172 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/gin-gonic/gin"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
-	_ "github.com/victorspringer/1g-take-home-task/docs"
+	"github.com/prometheus/client_golang/prometheus"
+	_ "github.com/victorspringer/1g-take-home-task/docs/v1"
+	_ "github.com/victorspringer/1g-take-home-task/docs/v2"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
 	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/lifecycle"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // @title Devices Service
 // @version 1.0
-// @description Devices Service for technical challenge.
+// @description Devices Service for technical challenge, version 1. The same routes are served, deprecated, without the /v1 prefix.
 // @contact.name Victor Springer
 // @license.name MIT License
 // @host localhost:8080
-// @BasePath /
+// @BasePath /v1
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+// Options are the dependencies Run serves with.
+type Options struct {
+	// Load re-reads the configuration on SIGHUP.
+	Load func() (config.Config, error)
+	// Logger is the service logger and LogLevel its level, swapped on reload.
+	Logger   *zap.Logger
+	LogLevel zap.AtomicLevel
+	Devices  device.Repository
+	Health   HealthChecker
+	// RateLimits keeps the rate limit counters; in memory when nil.
+	RateLimits ratelimit.Store
+	// Registry collects the service metrics; the Prometheus default registry
+	// when nil.
+	Registry *prometheus.Registry
+	// Lifecycle holds the components started before Run, such as the
+	// repository and background workers. Run adds the HTTP and gRPC servers,
+	// so they are stopped before them.
+	Lifecycle *lifecycle.Manager
+}
 
-// Run starts the HTTP server on specified port.
-func Run(port int, logger *zap.Logger, deviceRepository device.Repository) {
-	handler := &handler{
-		logger:           logger,
-		deviceRepository: deviceRepository,
+// Run serves the HTTP API, and the gRPC API when enabled, until the service is
+// asked to stop or a component fails, then shuts every component down and
+// returns the errors raised on the way.
+//
+// On SIGHUP the configuration is re-read and the log level, API keys and rate
+// limits are swapped in place. On SIGTERM/SIGINT readiness starts failing, and
+// after the configured drain delay the servers stop accepting connections and
+// the components are stopped in order within the shutdown timeout.
+func Run(cfg config.Config, opts Options) error {
+	logger := opts.Logger
+
+	s := newServer(cfg, opts)
+	settings := &reloadable{
+		load:     opts.Load,
+		logger:   logger,
+		logLevel: opts.LogLevel,
+		auth:     s.auth,
+		limits:   s.limits,
 	}
 
-	router := gin.New()
-	router.Use(gin.Recovery())
-
-	router.GET("/", handler.healthCheck)
-	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-
-	devices := router.Group("devices")
-
-	devices.GET("/", handler.listAllDevices)
-	devices.GET("/:id", handler.getDeviceByID)
-	devices.GET("/search", handler.searchDevices)
-
-	devices.POST("/", handler.addDevice)
-
-	devices.PATCH("/:id", handler.updateDevice)
-
-	devices.DELETE("/:id", handler.deleteDevice)
-
 	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: router,
+		Addr:              fmt.Sprintf(":%d", cfg.HTTP.Port),
+		Handler:           s.router,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		ErrorLog:          zap.NewStdLog(logger),
 	}
 
-	idleConnsClosed := make(chan struct{})
-
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
-		<-sigChan
+	var tlsConfig *tls.Config
+	serve := srv.ListenAndServe
+	if cfg.HTTP.TLS.Enabled() {
+		var err error
+		tlsConfig, err = newTLSConfig(cfg.HTTP.TLS, logger)
+		if err != nil {
+			// Release what main registered, since Run will not.
+			return errors.Join(fmt.Errorf("configuring TLS: %w", err), opts.Lifecycle.Shutdown())
+		}
+		srv.TLSConfig = tlsConfig
+		// The certificate comes from TLSConfig.GetCertificate.
+		serve = func() error { return srv.ListenAndServeTLS("", "") }
+	}
 
-		// Received an interrupt signal, shut down.
-		if err := srv.Shutdown(context.Background()); err != nil {
-			logger.With(zap.Error(err)).Error("error while attempting graceful shutdown")
+	opts.Lifecycle.Add("http server", func() error {
+		logger.With(zap.Int("port", cfg.HTTP.Port), zap.Bool("tls", cfg.HTTP.TLS.Enabled())).Info("starting http server")
+		if err := serve(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		logger.Info("server closed")
+		return nil
+	}, srv.Shutdown)
+
+	if cfg.GRPC.Enabled() {
+		service := newGRPCService(logger, s.events, s.events)
+		var serverOpts []grpc.ServerOption
+		if tlsConfig != nil {
+			serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
 		}
+		grpcServer := newGRPCServer(service, &grpcInterceptors{logger: logger, auth: s.auth, limits: s.limits}, serverOpts...)
+
+		// Added after the HTTP server, so it is stopped first.
+		opts.Lifecycle.Add("grpc server", func() error {
+			listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPC.Port))
+			if err != nil {
+				return err
+			}
+			logger.With(zap.Int("port", cfg.GRPC.Port), zap.Bool("tls", tlsConfig != nil)).Info("starting grpc server")
+			if err := grpcServer.Serve(listener); !errors.Is(err, grpc.ErrServerStopped) && err != nil {
+				return err
+			}
+			logger.Info("grpc server closed")
+			return nil
+		}, func(ctx context.Context) error {
+			return stopGRPC(ctx, grpcServer, service)
+		})
+	}
 
-		close(idleConnsClosed)
-	}()
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
 
-	logger.With(zap.Int("port", port)).Info("starting http server")
+	go handleSignals(ctx, stop, cfg.Shutdown.DrainDelay, logger, s.health, settings)
 
-	if err := srv.ListenAndServe(); errors.Is(err, http.ErrServerClosed) {
-		logger.Info("server closed")
-	} else {
-		logger.With(zap.Error(err)).Error("http server start/close error")
-	}
+	return opts.Lifecycle.Run(ctx)
+}
 
-	<-idleConnsClosed
+// handleSignals reloads settings on SIGHUP. On SIGTERM/SIGINT it fails
+// readiness first so traffic is routed elsewhere, waits drainDelay, then calls
+// shutdown. It returns once ctx is done.
+func handleSignals(ctx context.Context, shutdown context.CancelFunc, drainDelay time.Duration, logger *zap.Logger, health *health, settings *reloadable) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigChan:
+			if sig == syscall.SIGHUP {
+				settings.reload()
+				continue
+			}
+
+			health.draining.Store(true)
+			logger.With(zap.Duration("drainDelay", drainDelay)).Info("shutdown requested, draining")
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(drainDelay):
+			}
+			shutdown()
+			return
+		}
+	}
 }
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+
+	// authErrorKey is the gin context key under which identify stores why
+	// authentication failed.
+	authErrorKey = "authError"
+)
+
+// authenticator checks API keys against a set that can be replaced while
+// requests are being served. Callers presenting a client certificate verified
+// by mutual TLS are authenticated by it instead. With no keys configured every
+// request passes.
+type authenticator struct {
+	principals atomic.Pointer[map[string]string]
+}
+
+func newAuthenticator(principals map[string]string) *authenticator {
+	a := &authenticator{}
+	a.setPrincipals(principals)
+	return a
+}
+
+// setPrincipals swaps the accepted keys, mapped to the name of their owner.
+func (a *authenticator) setPrincipals(principals map[string]string) {
+	a.principals.Store(&principals)
+}
+
+var (
+	errMissingAPIKey = errors.New("missing API key")
+	errInvalidAPIKey = errors.New("invalid API key")
+)
+
+// authenticate resolves an API key to its owner. With no keys configured
+// every caller is let through anonymously.
+func (a *authenticator) authenticate(key string) (string, error) {
+	principals := *a.principals.Load()
+	if len(principals) == 0 {
+		return "", nil
+	}
+	if key == "" {
+		return "", errMissingAPIKey
+	}
+
+	principal, ok := lookupKey(principals, key)
+	if !ok {
+		return "", errInvalidAPIKey
+	}
+	return principal, nil
+}
+
+// identify records the request principal: the verified client certificate
+// or the owner of the key read from the X-API-Key header or an
+// "Authorization: Bearer" token. Requests failing authentication are let
+// through, so rate limiting counts them against the client IP, for require
+// to reject.
+func (a *authenticator) identify(c *gin.Context) {
+	if principal, ok := certificatePrincipal(c.Request.TLS); ok {
+		c.Set(principalKey, principal)
+		return
+	}
+
+	principal, err := a.authenticate(bearerKey(c.GetHeader(apiKeyHeader), c.GetHeader("Authorization")))
+	if err != nil {
+		c.Set(authErrorKey, err)
+		return
+	}
+
+	if principal != "" {
+		c.Set(principalKey, principal)
+	}
+}
+
+// require rejects requests identify could not authenticate.
+func (a *authenticator) require(c *gin.Context) {
+	if err, found := c.Get(authErrorKey); found {
+		c.Header("WWW-Authenticate", "Bearer")
+		abortWith(c, http.StatusUnauthorized, gin.H{
+			"error": err.(error).Error(),
+		})
+		return
+	}
+	c.Next()
+}
+
+// bearerKey returns the API key sent directly, or else as a bearer token.
+func bearerKey(apiKey, authorization string) string {
+	if apiKey != "" {
+		return apiKey
+	}
+	if token, found := strings.CutPrefix(authorization, "Bearer "); found {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// lookupKey compares key against every configured key in constant time, so
+// response timing does not reveal how much of a key matched.
+func lookupKey(principals map[string]string, key string) (string, bool) {
+	var principal string
+	found := false
+	for candidate, name := range principals {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			principal, found = name, true
+		}
+	}
+	return principal, found
+}
+
+// certificatePrincipal returns the common name of a verified client certificate.
+func certificatePrincipal(state *tls.ConnectionState) (string, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	name := state.VerifiedChains[0][0].Subject.CommonName
+	return "cert:" + name, name != ""
+}
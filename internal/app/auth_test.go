@@ -0,0 +1,73 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAuthRouter(auth *authenticator) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/devices", auth.identify, auth.require, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(principalKey))
+	})
+	return router
+}
+
+func authRequest(router *gin.Engine, header, value string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/devices", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuth_DisabledWithoutKeys(t *testing.T) {
+	router := setupAuthRouter(newAuthenticator(nil))
+
+	w := authRequest(router, "", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestAuth_AcceptsKeyHeaderAndBearerToken(t *testing.T) {
+	router := setupAuthRouter(newAuthenticator(map[string]string{"k1": "ci"}))
+
+	w := authRequest(router, apiKeyHeader, "k1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ci", w.Body.String())
+
+	w = authRequest(router, "Authorization", "Bearer k1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ci", w.Body.String())
+}
+
+func TestAuth_RejectsMissingAndInvalidKeys(t *testing.T) {
+	router := setupAuthRouter(newAuthenticator(map[string]string{"k1": "ci"}))
+
+	w := authRequest(router, "", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	assert.JSONEq(t, `{"error":"missing API key"}`, w.Body.String())
+
+	w = authRequest(router, apiKeyHeader, "k2")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"invalid API key"}`, w.Body.String())
+}
+
+func TestAuth_SetPrincipalsRotatesKeys(t *testing.T) {
+	auth := newAuthenticator(map[string]string{"old": "ci"})
+	router := setupAuthRouter(auth)
+
+	auth.setPrincipals(map[string]string{"new": "ci"})
+
+	assert.Equal(t, http.StatusUnauthorized, authRequest(router, apiKeyHeader, "old").Code)
+	assert.Equal(t, http.StatusOK, authRequest(router, apiKeyHeader, "new").Code)
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxBodyMiddleware caps request bodies at limit bytes. Requests declaring a
+// larger Content-Length are rejected with 413 before any handler runs; bodies
+// of unknown length fail while being read, which bindBody maps to 413 too.
+// A limit of 0 disables the cap.
+func maxBodyMiddleware(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			c.Header("Connection", "close")
+			abortWith(c, http.StatusRequestEntityTooLarge, gin.H{
+				"error": (&http.MaxBytesError{Limit: limit}).Error(),
+			})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// bindBody decodes the request body into obj in the encoding its
+// Content-Type names, answering 413 when the body exceeds the size limit and
+// 400 when it is malformed. It reports whether decoding succeeded.
+func bindBody(c *gin.Context, obj any) bool {
+	return bindWith(c, obj, requestFormat(c).binding)
+}
+
+// bindJSON is bindBody for endpoints that only accept JSON.
+func bindJSON(c *gin.Context, obj any) bool {
+	return bindWith(c, obj, binding.JSON)
+}
+
+func bindWith(c *gin.Context, obj any, b binding.Binding) bool {
+	err := c.ShouldBindWith(obj, b)
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respond(c, http.StatusRequestEntityTooLarge, gin.H{
+			"error": err.Error(),
+		})
+		return false
+	}
+
+	respond(c, http.StatusBadRequest, gin.H{
+		"error": err.Error(),
+	})
+	return false
+}
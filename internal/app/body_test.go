@@ -0,0 +1,60 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"go.uber.org/zap"
+)
+
+func setupBodyLimitedRouter(limit int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(maxBodyMiddleware(limit))
+
+	h := &handler{
+		logger:           zap.NewNop(),
+		deviceRepository: &device.MockRepository{},
+	}
+	router.POST("/devices", h.addDevice)
+
+	return router
+}
+
+func TestMaxBody_RejectsDeclaredLength(t *testing.T) {
+	router := setupBodyLimitedRouter(32)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/devices", strings.NewReader(`{"name":"Galaxy S23","brand":"Samsung"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"error":"http: request body too large"}`, w.Body.String())
+}
+
+func TestMaxBody_RejectsUnknownLength(t *testing.T) {
+	router := setupBodyLimitedRouter(32)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/devices", io.MultiReader(strings.NewReader(`{"name":"Galaxy S23",`), strings.NewReader(`"brand":"Samsung"}`)))
+	req.ContentLength = -1
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestMaxBody_AllowsSmallBodies(t *testing.T) {
+	router := setupBodyLimitedRouter(64)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/devices", strings.NewReader(`{"name":"Galaxy S23","brand":"Samsung"}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
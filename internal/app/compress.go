@@ -0,0 +1,221 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// minCompressSize is the smallest response body worth compressing; shorter
+// ones are sent as they are.
+const minCompressSize = 1024
+
+// compressor is a pooled gzip or zstd stream.
+type compressor interface {
+	io.Writer
+	Reset(w io.Writer)
+	Flush() error
+	Close() error
+}
+
+// codings lists the supported content codings, preferred first on ties.
+var codings = []struct {
+	name string
+	pool *sync.Pool
+}{
+	{"zstd", &sync.Pool{New: func() any {
+		// A single goroutine and an 8 MiB window suit short responses and
+		// stay within what browsers accept.
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(8<<20))
+		return enc
+	}}},
+	{"gzip", &sync.Pool{New: func() any {
+		return gzip.NewWriter(nil)
+	}}},
+}
+
+// compressMiddleware compresses response bodies with zstd or gzip, whichever
+// the Accept-Encoding header prefers. Bodies shorter than minCompressSize,
+// already encoded or of incompressible types are sent unchanged. Compressed
+// responses get a weak ETag, since their bytes differ from the identity
+// representation the ETag was computed for.
+func compressMiddleware(c *gin.Context) {
+	c.Writer.Header().Add("Vary", "Accept-Encoding")
+
+	coding := negotiateCoding(c.GetHeader("Accept-Encoding"))
+	if coding < 0 {
+		c.Next()
+		return
+	}
+
+	w := &compressWriter{ResponseWriter: c.Writer, coding: coding}
+	c.Writer = w
+	defer func() {
+		w.close()
+		c.Writer = w.ResponseWriter
+	}()
+	c.Next()
+}
+
+// negotiateCoding returns the index in codings of the coding the
+// Accept-Encoding header prefers, or -1 if it accepts none of them.
+func negotiateCoding(header string) int {
+	best, bestQ := -1, 0.0
+	wildcard := -1.0
+	q := make([]float64, len(codings))
+	for i := range q {
+		q[i] = -1
+	}
+
+	for _, entry := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(entry, ";")
+		weight := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "*" {
+			wildcard = weight
+		}
+		for i, coding := range codings {
+			if coding.name == name {
+				q[i] = weight
+			}
+		}
+	}
+
+	for i := range codings {
+		weight := q[i]
+		if weight < 0 {
+			weight = wildcard
+		}
+		if weight > bestQ {
+			best, bestQ = i, weight
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of the body until it knows whether to
+// compress it, then streams through the negotiated compressor.
+type compressWriter struct {
+	gin.ResponseWriter
+	coding  int
+	buf     []byte
+	decided bool
+	enc     compressor
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		if len(w.buf)+len(data) < minCompressSize {
+			w.buf = append(w.buf, data...)
+			return len(data), nil
+		}
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow commits the headers before the body is known, as handlers
+// streaming their response do; such responses are not compressed.
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.start(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.start(true)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// start decides whether to compress, adjusting the headers accordingly, and
+// writes out the buffered start of the body.
+func (w *compressWriter) start(compress bool) error {
+	w.decided = true
+
+	header := w.Header()
+	if compress && w.compressible(header) {
+		header.Set("Content-Encoding", codings[w.coding].name)
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		w.enc = codings[w.coding].pool.Get().(compressor)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	_, err := w.Write(buf)
+	return err
+}
+
+func (w *compressWriter) compressible(header http.Header) bool {
+	status := w.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType, _, _ := strings.Cut(header.Get("Content-Type"), ";")
+	contentType = strings.TrimSpace(contentType)
+	switch {
+	case strings.HasPrefix(contentType, "text/"),
+		strings.HasSuffix(contentType, "json"),
+		strings.HasSuffix(contentType, "xml"),
+		strings.HasSuffix(contentType, "yaml"),
+		contentType == "application/javascript",
+		contentType == mimeMsgPack,
+		contentType == mimeCBOR:
+		return true
+	}
+	return false
+}
+
+// close sends a short body as it is, or finishes the compressed stream.
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.start(false)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(nil)
+		codings[w.coding].pool.Put(w.enc)
+		w.enc = nil
+	}
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateCoding(t *testing.T) {
+	tests := map[string]string{
+		"":                        "",
+		"identity":                "",
+		"gzip":                    "gzip",
+		"gzip, deflate, br, zstd": "zstd",
+		"zstd;q=0.5, gzip":        "gzip",
+		"GZIP;q=0.8":              "gzip",
+		"*":                       "zstd",
+		"*;q=0.1, zstd;q=0":       "gzip",
+		"gzip;q=0":                "",
+		"gzip;q=bad":              "",
+	}
+	for header, want := range tests {
+		got := ""
+		if i := negotiateCoding(header); i >= 0 {
+			got = codings[i].name
+		}
+		assert.Equal(t, want, got, header)
+	}
+}
+
+func setupCompressedRouter(body string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(compressMiddleware)
+	router.GET("/text", func(c *gin.Context) {
+		c.Header("ETag", `"v1"`)
+		c.String(http.StatusOK, body)
+	})
+	router.GET("/binary", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(body))
+	})
+	router.GET("/empty", func(c *gin.Context) {
+		c.Status(http.StatusNotModified)
+	})
+	return router
+}
+
+func compressedRequest(router http.Handler, path, acceptEncoding string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	body := strings.Repeat("device ", 1000)
+	router := setupCompressedRouter(body)
+
+	w := compressedRequest(router, "/text", "gzip")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, `W/"v1"`, w.Header().Get("ETag"))
+	assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+	assert.Less(t, w.Body.Len(), len(body))
+	gz, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(decoded))
+
+	// The pooled encoders are reused across responses.
+	for range 2 {
+		w = compressedRequest(router, "/text", "zstd")
+		assert.Equal(t, "zstd", w.Header().Get("Content-Encoding"))
+		zr, err := zstd.NewReader(w.Body)
+		require.NoError(t, err)
+		decoded, err = io.ReadAll(zr)
+		zr.Close()
+		require.NoError(t, err)
+		assert.Equal(t, body, string(decoded))
+	}
+
+	w = compressedRequest(router, "/text", "")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+	assert.Equal(t, body, w.Body.String())
+
+	w = compressedRequest(router, "/binary", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"), "incompressible type")
+	assert.Equal(t, body, w.Body.String())
+
+	w = compressedRequest(router, "/empty", "gzip")
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestCompressMiddleware_SmallBody(t *testing.T) {
+	router := setupCompressedRouter("short")
+
+	w := compressedRequest(router, "/text", "gzip, zstd")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "short", w.Body.String())
+}
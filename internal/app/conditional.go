@@ -0,0 +1,148 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+)
+
+// deviceETag returns the ETag of a response holding devices, which changes
+// whenever a device is added, removed or updated, or extra (such as the next
+// page token) changes. Each encoding is a representation of its own, with
+// its own ETag.
+func deviceETag(c *gin.Context, extra string, devices ...device.Device) string {
+	hash := sha256.New()
+	for _, dvc := range devices {
+		hash.Write([]byte(dvc.ID + "@" + strconv.FormatInt(dvc.UpdateTime.UnixNano(), 36) + "\n"))
+	}
+	hash.Write([]byte(responseFormat(c).contentType + "\n" + extra))
+
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets the ETag of a response listing devices and, when
+// If-None-Match shows the client already has it, answers 304 and returns
+// true. Listings carry no Last-Modified: removing a device changes them
+// without advancing any update time.
+func notModified(c *gin.Context, extra string, devices ...device.Device) bool {
+	etag := deviceETag(c, extra, devices...)
+	c.Header("ETag", etag)
+
+	if !etagMatches(c.GetHeader("If-None-Match"), etag) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// deviceNotModified is notModified for a single device, which also carries
+// its update time as Last-Modified. If-None-Match takes precedence over
+// If-Modified-Since, as in RFC 9110.
+func deviceNotModified(c *gin.Context, extra string, dvc device.Device) bool {
+	etag := deviceETag(c, extra, dvc)
+	c.Header("ETag", etag)
+	if !dvc.UpdateTime.IsZero() {
+		c.Header("Last-Modified", dvc.UpdateTime.UTC().Format(http.TimeFormat))
+	}
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err != nil || dvc.UpdateTime.IsZero() || dvc.UpdateTime.Truncate(time.Second).After(since) {
+		return false
+	}
+
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using the
+// weak comparison GET requests call for.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheControl sets the Cache-Control header configured for each route, in
+// every API version, on its 200 and 304 responses. Errors and rejections,
+// such as 401, 404 or 429, are not to be stored by shared caches.
+type cacheControl struct {
+	routes map[string]string
+}
+
+func newCacheControl(cfg config.HTTP) *cacheControl {
+	routes, _ := cfg.CacheControlRoutes()
+	return &cacheControl{routes: routes}
+}
+
+func (cc *cacheControl) middleware(c *gin.Context) {
+	value, found := cc.routes[c.Request.Method+" "+unversionedRoute(c)]
+	if !found {
+		c.Next()
+		return
+	}
+
+	w := &cacheControlWriter{ResponseWriter: c.Writer, value: value}
+	c.Writer = w
+	defer func() {
+		c.Writer = w.ResponseWriter
+	}()
+	c.Next()
+
+	// Responses without a body, such as 304, are committed after the
+	// handlers return.
+	if !w.Written() {
+		w.setHeader()
+	}
+}
+
+// cacheControlWriter adds the Cache-Control header once the status is known,
+// just before the headers are committed.
+type cacheControlWriter struct {
+	gin.ResponseWriter
+	value string
+	done  bool
+}
+
+func (w *cacheControlWriter) setHeader() {
+	if w.done {
+		return
+	}
+	w.done = true
+	if status := w.Status(); status == http.StatusOK || status == http.StatusNotModified {
+		w.Header().Set("Cache-Control", w.value)
+	}
+}
+
+func (w *cacheControlWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cacheControlWriter) WriteString(s string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *cacheControlWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *cacheControlWriter) Flush() {
+	w.setHeader()
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"go.uber.org/zap"
+)
+
+func conditionalRequest(router http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetDeviceByID_Conditional(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 12, 30, 15, 500, time.UTC)
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA", UpdateTime: updated},
+		},
+	}
+	router := setupRouter(repo)
+
+	w := conditionalRequest(router, "/devices/1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "Wed, 01 May 2024 12:30:15 GMT", w.Header().Get("Last-Modified"))
+
+	w = conditionalRequest(router, "/devices/1", map[string]string{"If-None-Match": `"other", W/` + etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = conditionalRequest(router, "/devices/1", map[string]string{"If-Modified-Since": "Wed, 01 May 2024 12:30:15 GMT"})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// If-None-Match wins over If-Modified-Since.
+	w = conditionalRequest(router, "/devices/1", map[string]string{
+		"If-None-Match":     `"other"`,
+		"If-Modified-Since": "Wed, 01 May 2024 12:30:15 GMT",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	repo.Devices[0].UpdateTime = updated.Add(time.Second)
+	w = conditionalRequest(router, "/devices/1", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	w = conditionalRequest(router, "/devices/1", map[string]string{"If-Modified-Since": "Wed, 01 May 2024 12:30:15 GMT"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestListAllDevices_Conditional(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA", UpdateTime: time.Unix(100, 0)},
+			{ID: "2", Name: "Device2", Brand: "BrandB", UpdateTime: time.Unix(200, 0)},
+		},
+	}
+	router := setupRouter(repo)
+
+	w := conditionalRequest(router, "/devices", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Empty(t, w.Header().Get("Last-Modified"))
+
+	assert.Equal(t, http.StatusNotModified, conditionalRequest(router, "/devices", map[string]string{"If-None-Match": etag}).Code)
+
+	page := conditionalRequest(router, "/devices?pageSize=1", nil)
+	require.Equal(t, http.StatusOK, page.Code)
+	assert.NotEqual(t, etag, page.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, conditionalRequest(router, "/devices?pageSize=1", map[string]string{"If-None-Match": page.Header().Get("ETag")}).Code)
+
+	// Removing a device changes the ETag; If-Modified-Since, which could
+	// not tell, is ignored.
+	repo.Devices = repo.Devices[1:]
+	w = conditionalRequest(router, "/devices", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = conditionalRequest(router, "/devices", map[string]string{"If-Modified-Since": time.Unix(200, 0).UTC().Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCacheControl_PerRoute(t *testing.T) {
+	cc := newCacheControl(config.HTTP{CacheControl: []string{"GET /devices/:id=private max-age=60"}})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(cc.middleware)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/devices", ok)
+	router.GET("/devices/:id", ok)
+
+	assert.Equal(t, "private, max-age=60", conditionalRequest(router, "/devices/1", nil).Header().Get("Cache-Control"))
+	assert.Empty(t, conditionalRequest(router, "/devices", nil).Header().Get("Cache-Control"))
+}
+
+func TestCacheControl_OnlyOnSuccess(t *testing.T) {
+	cfg := config.Default()
+	cfg.HTTP.CacheControl = []string{"GET /devices/:id=public max-age=3600"}
+	cfg.Auth.APIKeys = []string{"ci:k1"}
+	router := NewRouter(cfg, Options{
+		Logger: zap.NewNop(),
+		Devices: &device.MockRepository{
+			Devices: []device.Device{{ID: "1", Name: "Device1", Brand: "BrandA"}},
+		},
+		Registry: prometheus.NewRegistry(),
+	})
+	key := map[string]string{apiKeyHeader: "k1"}
+
+	w := conditionalRequest(router, "/v1/devices/1", key)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=3600", w.Header().Get("Cache-Control"))
+
+	w = conditionalRequest(router, "/v1/devices/1", map[string]string{apiKeyHeader: "k1", "If-None-Match": w.Header().Get("ETag")})
+	require.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, "public, max-age=3600", w.Header().Get("Cache-Control"))
+
+	w = conditionalRequest(router, "/v1/devices/2", key)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Cache-Control"))
+
+	w = conditionalRequest(router, "/v1/devices/1", nil)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Header().Get("Cache-Control"))
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Media types of the alternate encodings of the REST API. Requests and
+// responses use JSON unless Content-Type or Accept select one of these.
+const (
+	mimeMsgPack = "application/msgpack"
+	mimeCBOR    = "application/cbor"
+)
+
+// format encodes REST API bodies in one media type.
+type format struct {
+	contentType string
+	// marshal encodes responses; nil leaves them to gin's JSON renderer.
+	marshal func(any) ([]byte, error)
+	binding binding.Binding
+}
+
+var (
+	jsonFormat = format{
+		contentType: binding.MIMEJSON,
+		binding:     binding.JSON,
+	}
+	msgpackFormat = format{
+		contentType: mimeMsgPack,
+		marshal:     marshalMsgPack,
+		binding:     codecBinding{name: "msgpack", unmarshal: unmarshalMsgPack},
+	}
+	cborFormat = format{
+		contentType: mimeCBOR,
+		marshal:     cborEncoding.Marshal,
+		binding:     codecBinding{name: "cbor", unmarshal: cbor.Unmarshal},
+	}
+)
+
+// render returns the renderer writing obj in this format.
+func (f format) render(obj any) render.Render {
+	if f.marshal == nil {
+		return render.JSON{Data: obj}
+	}
+	return codecRender{contentType: f.contentType, marshal: f.marshal, data: obj}
+}
+
+// cborEncoding writes times as RFC 3339 strings, like JSON, tagged so CBOR
+// decoders read them back as times.
+var cborEncoding, _ = cbor.EncOptions{
+	Time:    cbor.TimeRFC3339Nano,
+	TimeTag: cbor.EncTagRequired,
+}.EncMode()
+
+// responseFormat negotiates the encoding of the response from the Accept
+// header, falling back to JSON when it names none of the supported types.
+func responseFormat(c *gin.Context) format {
+	switch c.NegotiateFormat(binding.MIMEJSON, mimeMsgPack, binding.MIMEMSGPACK, mimeCBOR) {
+	case mimeMsgPack, binding.MIMEMSGPACK:
+		return msgpackFormat
+	case mimeCBOR:
+		return cborFormat
+	default:
+		return jsonFormat
+	}
+}
+
+// requestFormat picks the decoder of the request body from its Content-Type,
+// treating anything unknown as JSON.
+func requestFormat(c *gin.Context) format {
+	switch c.ContentType() {
+	case mimeMsgPack, binding.MIMEMSGPACK:
+		return msgpackFormat
+	case mimeCBOR:
+		return cborFormat
+	default:
+		return jsonFormat
+	}
+}
+
+// respond writes obj with the status code in the encoding the client
+// accepts, wrapped in the envelope of the API version serving the request.
+func respond(c *gin.Context, status int, obj any) {
+	c.Writer.Header().Add("Vary", "Accept")
+	if apiVersion(c) >= 2 {
+		obj = envelope(status, obj)
+	}
+	c.Render(status, responseFormat(c).render(obj))
+}
+
+// abortWith stops the handler chain and responds with obj.
+func abortWith(c *gin.Context, status int, obj any) {
+	c.Abort()
+	respond(c, status, obj)
+}
+
+// codecRender renders data with a marshal function, as gin's own renderers do.
+type codecRender struct {
+	contentType string
+	marshal     func(any) ([]byte, error)
+	data        any
+}
+
+func (r codecRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	data, err := r.marshal(r.data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (r codecRender) WriteContentType(w http.ResponseWriter) {
+	if header := w.Header(); len(header["Content-Type"]) == 0 {
+		header["Content-Type"] = []string{r.contentType}
+	}
+}
+
+// codecBinding decodes request bodies with an unmarshal function and
+// validates them like gin's JSON binding.
+type codecBinding struct {
+	name      string
+	unmarshal func([]byte, any) error
+}
+
+func (b codecBinding) Name() string {
+	return b.name
+}
+
+func (b codecBinding) Bind(req *http.Request, obj any) error {
+	var body bytes.Buffer
+	if req.Body != nil {
+		if _, err := io.Copy(&body, req.Body); err != nil {
+			return err
+		}
+	}
+	return b.BindBody(body.Bytes(), obj)
+}
+
+func (b codecBinding) BindBody(body []byte, obj any) error {
+	if err := b.unmarshal(body, obj); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// marshalMsgPack names struct fields by their json tags, so MessagePack
+// bodies have the same shape as JSON ones. Times use the timestamp extension.
+func marshalMsgPack(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalMsgPack(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
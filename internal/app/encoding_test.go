@@ -0,0 +1,82 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+)
+
+func TestGetDeviceByID_Encodings(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA", CreationTime: created, UpdateTime: created},
+		},
+	}
+	router := setupRouter(repo)
+
+	decoders := map[string]func([]byte, any) error{
+		mimeMsgPack: unmarshalMsgPack,
+		mimeCBOR:    cbor.Unmarshal,
+	}
+	etags := map[string]bool{}
+	for accept, decode := range decoders {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/devices/1", nil)
+		req.Header.Set("Accept", accept)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, accept)
+		assert.Equal(t, accept, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Values("Vary"), "Accept")
+		etags[w.Header().Get("ETag")] = true
+
+		var body struct {
+			Device device.Device `json:"device"`
+		}
+		require.NoError(t, decode(w.Body.Bytes(), &body), accept)
+		assert.Equal(t, "Device1", body.Device.Name, accept)
+		assert.True(t, created.Equal(body.Device.CreationTime), accept)
+	}
+
+	w := conditionalRequest(router, "/devices/1", map[string]string{"Accept": "text/html, */*"})
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	etags[w.Header().Get("ETag")] = true
+	assert.Len(t, etags, 3, "each encoding has its own ETag")
+}
+
+func TestAddDevice_Encodings(t *testing.T) {
+	for contentType, encode := range map[string]func(any) ([]byte, error){
+		mimeMsgPack: marshalMsgPack,
+		mimeCBOR:    cbor.Marshal,
+	} {
+		repo := &device.MockRepository{}
+		router := setupRouter(repo)
+
+		body, err := encode(map[string]string{"name": "Device1", "brand": "BrandA"})
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/devices", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code, contentType)
+		require.Len(t, repo.Devices, 1, contentType)
+		assert.Equal(t, "BrandA", repo.Devices[0].Brand, contentType)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/devices", bytes.NewReader([]byte{0xc1}))
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, contentType)
+	}
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"google.golang.org/grpc/codes"
+)
+
+// errorCodes maps repository errors to the status codes of the REST, gRPC and
+// GraphQL APIs, so all of them report failures the same way. Other errors are
+// internal.
+var errorCodes = []struct {
+	err         error
+	httpCode    int
+	grpcCode    codes.Code
+	graphqlCode string
+}{
+	{device.ErrNotFound, http.StatusNotFound, codes.NotFound, codeNotFound},
+	{device.ErrMissingID, http.StatusBadRequest, codes.InvalidArgument, codeBadUserInput},
+	{device.ErrInvalidUpdate, http.StatusBadRequest, codes.InvalidArgument, codeBadUserInput},
+	{device.ErrInvalidPageToken, http.StatusBadRequest, codes.InvalidArgument, codeBadUserInput},
+	{device.ErrInvalidInterval, http.StatusBadRequest, codes.InvalidArgument, codeBadUserInput},
+	{device.ErrInvalidField, http.StatusBadRequest, codes.InvalidArgument, codeBadUserInput},
+}
+
+// GraphQL error codes, reported in the "code" extension of errors.
+const (
+	codeNotFound     = "NOT_FOUND"
+	codeBadUserInput = "BAD_USER_INPUT"
+	codeInternal     = "INTERNAL_SERVER_ERROR"
+)
+
+// httpStatus returns the REST status code for a repository error.
+func httpStatus(err error) int {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.httpCode
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// grpcCode returns the gRPC status code for a repository error.
+func grpcCode(err error) codes.Code {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.grpcCode
+		}
+	}
+	return codes.Internal
+}
+
+// graphqlCode returns the GraphQL error code for a repository error.
+func graphqlCode(err error) string {
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.graphqlCode
+		}
+	}
+	return codeInternal
+}
@@ -0,0 +1,341 @@
+package app
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/dataloader"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/logging"
+	"go.uber.org/zap"
+)
+
+//go:embed schema.graphql
+var graphqlSchema string
+
+const (
+	// graphqlBatchWait is how long loaders collect keys before fetching them.
+	graphqlBatchWait = time.Millisecond
+	// graphqlMaxBatch caps the keys fetched by one repository call.
+	graphqlMaxBatch = 500
+	// graphqlMaxDepth rejects queries nested deeper than this.
+	graphqlMaxDepth = 10
+)
+
+// graphqlHandler serves GraphQL queries over the device repository.
+type graphqlHandler struct {
+	logger           *zap.Logger
+	deviceRepository device.Repository
+	schema           *graphql.Schema
+}
+
+func newGraphQLHandler(logger *zap.Logger, deviceRepository device.Repository) *graphqlHandler {
+	h := &graphqlHandler{
+		logger:           logger,
+		deviceRepository: deviceRepository,
+	}
+	h.schema = graphql.MustParseSchema(graphqlSchema, &graphqlResolver{h},
+		graphql.MaxDepth(graphqlMaxDepth),
+		graphql.Logger(h),
+	)
+	return h
+}
+
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// @Summary GraphQL
+// @Description Run a GraphQL query or mutation over devices
+// @ID graphql
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 413
+// @Failure 429
+// @Router /graphql [post]
+func (h *graphqlHandler) serve(c *gin.Context) {
+	var req graphqlRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ctx := h.withLoaders(c.Request.Context())
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	for _, err := range resp.Errors {
+		if err.Extensions["code"] == codeInternal {
+			h.log(c).Error("error resolving graphql query", zap.String("path", fmt.Sprint(err.Path)), zap.Error(err.ResolverError))
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// log returns the request-scoped logger.
+func (h *graphqlHandler) log(c *gin.Context) *zap.Logger {
+	return logging.FromContext(c.Request.Context(), h.logger)
+}
+
+// LogPanic logs a panic raised by a resolver; the query gets an error for it.
+func (h *graphqlHandler) LogPanic(ctx context.Context, value any) {
+	logging.FromContext(ctx, h.logger).Error("panic resolving graphql query", zap.Any("panic", value), zap.Stack("stack"))
+}
+
+// graphqlLoaders batch the lookups of one request.
+type graphqlLoaders struct {
+	devices     *dataloader.Loader[string, device.Device]
+	brandCounts *dataloader.Loader[string, int]
+}
+
+type graphqlLoadersKey struct{}
+
+// withLoaders returns a copy of ctx carrying fresh loaders, so results are
+// only shared within a request.
+func (h *graphqlHandler) withLoaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, graphqlLoadersKey{}, &graphqlLoaders{
+		devices: dataloader.New(func(ctx context.Context, ids []string) (map[string]device.Device, error) {
+			devices, err := h.deviceRepository.FindByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			found := make(map[string]device.Device, len(devices))
+			for _, dvc := range devices {
+				found[dvc.ID] = dvc
+			}
+			return found, nil
+		}, graphqlBatchWait, graphqlMaxBatch),
+		// One count query answers every brand requested together.
+		brandCounts: dataloader.New(func(ctx context.Context, _ []string) (map[string]int, error) {
+			return h.deviceRepository.CountByBrand(ctx)
+		}, graphqlBatchWait, 0),
+	})
+}
+
+func graphqlLoadersFrom(ctx context.Context) *graphqlLoaders {
+	return ctx.Value(graphqlLoadersKey{}).(*graphqlLoaders)
+}
+
+var errNegativeFirst = errors.New("first must not be negative")
+
+// graphqlError reports err with an error code in the response extensions.
+type graphqlError struct {
+	err  error
+	code string
+}
+
+// graphqlRepositoryError wraps err with the code errorCodes maps it to.
+func graphqlRepositoryError(err error) error {
+	return graphqlError{err: err, code: graphqlCode(err)}
+}
+
+func (e graphqlError) Error() string {
+	return e.err.Error()
+}
+
+func (e graphqlError) Unwrap() error {
+	return e.err
+}
+
+func (e graphqlError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
+
+// graphqlResolver resolves the Query and Mutation root fields.
+type graphqlResolver struct {
+	h *graphqlHandler
+}
+
+func (r *graphqlResolver) Device(ctx context.Context, args struct{ ID graphql.ID }) (*deviceResolver, error) {
+	dvc, found, err := graphqlLoadersFrom(ctx).devices.Load(ctx, string(args.ID))
+	if err != nil {
+		return nil, graphqlRepositoryError(err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &deviceResolver{dvc}, nil
+}
+
+func (r *graphqlResolver) Devices(ctx context.Context, args struct {
+	Brand *string
+	First *int32
+	After *string
+}) (*deviceConnectionResolver, error) {
+	options := device.ListOptions{PageSize: maxPageSize}
+	if args.Brand != nil {
+		options.Brand = *args.Brand
+	}
+	if args.After != nil {
+		options.PageToken = *args.After
+	}
+	if args.First != nil {
+		if *args.First < 0 {
+			return nil, graphqlError{err: errNegativeFirst, code: codeBadUserInput}
+		}
+		if *args.First == 0 {
+			// Nothing to list, but a malformed cursor is still an error.
+			if _, err := device.ParseCursor(options.PageToken); err != nil {
+				return nil, graphqlRepositoryError(err)
+			}
+			return &deviceConnectionResolver{}, nil
+		}
+		options.PageSize = min(int(*args.First), maxPageSize)
+	}
+
+	page, err := r.h.deviceRepository.ListPage(ctx, options)
+	if err != nil {
+		return nil, graphqlRepositoryError(err)
+	}
+	return &deviceConnectionResolver{page}, nil
+}
+
+func (r *graphqlResolver) Brands(ctx context.Context) ([]*brandResolver, error) {
+	counts, err := r.h.deviceRepository.CountByBrand(ctx)
+	if err != nil {
+		return nil, graphqlRepositoryError(err)
+	}
+
+	brands := make([]*brandResolver, 0, len(counts))
+	for name, count := range counts {
+		brands = append(brands, &brandResolver{name: name, count: &count})
+	}
+	sort.Slice(brands, func(i, j int) bool { return brands[i].name < brands[j].name })
+	return brands, nil
+}
+
+func (r *graphqlResolver) CreateDevice(ctx context.Context, args struct {
+	Input struct {
+		Name  string
+		Brand string
+	}
+}) (*deviceResolver, error) {
+	dvc := device.Device{Name: args.Input.Name, Brand: args.Input.Brand}
+	if err := r.h.deviceRepository.Store(ctx, &dvc); err != nil {
+		return nil, graphqlRepositoryError(err)
+	}
+	return &deviceResolver{dvc}, nil
+}
+
+func (r *graphqlResolver) UpdateDevice(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input struct {
+		Name  *string
+		Brand *string
+	}
+}) (*deviceResolver, error) {
+	dvc := device.Device{ID: string(args.ID)}
+	if args.Input.Name != nil {
+		dvc.Name = *args.Input.Name
+	}
+	if args.Input.Brand != nil {
+		dvc.Brand = *args.Input.Brand
+	}
+	if err := r.h.deviceRepository.Update(ctx, &dvc); err != nil {
+		return nil, graphqlRepositoryError(err)
+	}
+
+	updated, err := r.h.deviceRepository.FindByID(ctx, dvc.ID)
+	if err != nil {
+		return nil, graphqlRepositoryError(err)
+	}
+	if updated == nil {
+		return nil, graphqlRepositoryError(device.ErrNotFound)
+	}
+	return &deviceResolver{*updated}, nil
+}
+
+func (r *graphqlResolver) DeleteDevice(ctx context.Context, args struct{ ID graphql.ID }) (graphql.ID, error) {
+	if err := r.h.deviceRepository.Remove(ctx, string(args.ID)); err != nil {
+		return "", graphqlRepositoryError(err)
+	}
+	return args.ID, nil
+}
+
+type deviceResolver struct {
+	dvc device.Device
+}
+
+func (r *deviceResolver) ID() graphql.ID {
+	return graphql.ID(r.dvc.ID)
+}
+
+func (r *deviceResolver) Name() string {
+	return r.dvc.Name
+}
+
+func (r *deviceResolver) Brand() *brandResolver {
+	return &brandResolver{name: r.dvc.Brand}
+}
+
+func (r *deviceResolver) CreationTime() graphql.Time {
+	return graphql.Time{Time: r.dvc.CreationTime}
+}
+
+func (r *deviceResolver) UpdateTime() graphql.Time {
+	return graphql.Time{Time: r.dvc.UpdateTime}
+}
+
+// brandResolver resolves a brand; its count is loaded unless already known.
+type brandResolver struct {
+	name  string
+	count *int
+}
+
+func (r *brandResolver) Name() string {
+	return r.name
+}
+
+func (r *brandResolver) DeviceCount(ctx context.Context) (int32, error) {
+	if r.count != nil {
+		return int32(*r.count), nil
+	}
+
+	count, _, err := graphqlLoadersFrom(ctx).brandCounts.Load(ctx, r.name)
+	if err != nil {
+		return 0, graphqlRepositoryError(err)
+	}
+	return int32(count), nil
+}
+
+type deviceConnectionResolver struct {
+	page device.Page
+}
+
+func (r *deviceConnectionResolver) Nodes() []*deviceResolver {
+	nodes := make([]*deviceResolver, len(r.page.Devices))
+	for i, dvc := range r.page.Devices {
+		nodes[i] = &deviceResolver{dvc}
+	}
+	return nodes
+}
+
+func (r *deviceConnectionResolver) PageInfo() *pageInfoResolver {
+	return &pageInfoResolver{r.page.NextPageToken}
+}
+
+type pageInfoResolver struct {
+	nextPageToken string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool {
+	return r.nextPageToken != ""
+}
+
+func (r *pageInfoResolver) EndCursor() *string {
+	if r.nextPageToken == "" {
+		return nil
+	}
+	return &r.nextPageToken
+}
@@ -0,0 +1,194 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"go.uber.org/zap"
+)
+
+// countingRepository counts the batch lookups GraphQL resolvers make.
+type countingRepository struct {
+	device.MockRepository
+	findByIDs    atomic.Int32
+	countByBrand atomic.Int32
+}
+
+func (r *countingRepository) FindByIDs(ctx context.Context, ids []string) ([]device.Device, error) {
+	r.findByIDs.Add(1)
+	return r.MockRepository.FindByIDs(ctx, ids)
+}
+
+func (r *countingRepository) CountByBrand(ctx context.Context, brands ...string) (map[string]int, error) {
+	r.countByBrand.Add(1)
+	return r.MockRepository.CountByBrand(ctx, brands...)
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func graphqlQuery(t *testing.T, repo device.Repository, query string, variables map[string]any) graphqlResponse {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/graphql", newGraphQLHandler(zap.NewNop(), repo).serve)
+
+	body, err := json.Marshal(graphqlRequest{Query: query, Variables: variables})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp graphqlResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestGraphQL_BatchesLookups(t *testing.T) {
+	repo := &countingRepository{}
+	ctx := context.Background()
+	pixel := &device.Device{Name: "Pixel 8", Brand: "Google"}
+	iphone := &device.Device{Name: "iPhone 15", Brand: "Apple"}
+	require.NoError(t, repo.Store(ctx, pixel))
+	require.NoError(t, repo.Store(ctx, iphone))
+
+	resp := graphqlQuery(t, repo, `query($a: ID!, $b: ID!) {
+		a: device(id: $a) { name brand { name deviceCount } }
+		b: device(id: $b) { name brand { name deviceCount } }
+		missing: device(id: "missing") { name }
+	}`, map[string]any{"a": pixel.ID, "b": iphone.ID})
+
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{
+		"a": {"name": "Pixel 8", "brand": {"name": "Google", "deviceCount": 1}},
+		"b": {"name": "iPhone 15", "brand": {"name": "Apple", "deviceCount": 1}},
+		"missing": null
+	}`, string(resp.Data))
+	assert.Equal(t, int32(1), repo.findByIDs.Load(), "device lookups are batched")
+	assert.Equal(t, int32(1), repo.countByBrand.Load(), "brand counts are batched")
+}
+
+func TestGraphQL_DevicesPaginates(t *testing.T) {
+	repo := &device.MockRepository{}
+	ctx := context.Background()
+	for _, dvc := range []device.Device{
+		{Name: "Pixel 7", Brand: "Google"},
+		{Name: "Galaxy S24", Brand: "Samsung"},
+		{Name: "Pixel 8", Brand: "Google"},
+	} {
+		require.NoError(t, repo.Store(ctx, &dvc))
+	}
+
+	const query = `query($after: String) {
+		devices(brand: "Google", first: 1, after: $after) {
+			nodes { name }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+
+	var first struct {
+		Devices struct {
+			Nodes    []struct{ Name string }
+			PageInfo struct {
+				HasNextPage bool
+				EndCursor   string
+			}
+		}
+	}
+	resp := graphqlQuery(t, repo, query, nil)
+	require.Empty(t, resp.Errors)
+	require.NoError(t, json.Unmarshal(resp.Data, &first))
+	require.Len(t, first.Devices.Nodes, 1)
+	assert.Equal(t, "Pixel 7", first.Devices.Nodes[0].Name)
+	assert.True(t, first.Devices.PageInfo.HasNextPage)
+
+	resp = graphqlQuery(t, repo, query, map[string]any{"after": first.Devices.PageInfo.EndCursor})
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"devices": {"nodes": [{"name": "Pixel 8"}], "pageInfo": {"hasNextPage": false, "endCursor": null}}}`, string(resp.Data))
+
+	resp = graphqlQuery(t, repo, `{ devices(after: "not a token") { nodes { name } } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "BAD_USER_INPUT", resp.Errors[0].Extensions["code"])
+
+	resp = graphqlQuery(t, repo, `{ devices(first: 0) { nodes { name } pageInfo { hasNextPage } } }`, nil)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"devices": {"nodes": [], "pageInfo": {"hasNextPage": false}}}`, string(resp.Data))
+
+	resp = graphqlQuery(t, repo, `{ devices(first: 0, after: "not a token") { nodes { name } } }`, nil)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "BAD_USER_INPUT", resp.Errors[0].Extensions["code"])
+
+	resp = graphqlQuery(t, repo, `{ devices(first: 5000) { nodes { name } } }`, nil)
+	require.Empty(t, resp.Errors)
+	assert.Contains(t, string(resp.Data), "Galaxy S24")
+}
+
+func TestGraphQL_Mutations(t *testing.T) {
+	repo := &device.MockRepository{}
+
+	resp := graphqlQuery(t, repo, `mutation { createDevice(input: {name: "Pixel 8", brand: "Google"}) { id } }`, nil)
+	require.Empty(t, resp.Errors)
+	require.Len(t, repo.Devices, 1)
+	id := repo.Devices[0].ID
+
+	resp = graphqlQuery(t, repo, `mutation($id: ID!) { updateDevice(id: $id, input: {name: "Pixel 8 Pro"}) { name brand { name } } }`, map[string]any{"id": id})
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"updateDevice": {"name": "Pixel 8 Pro", "brand": {"name": "Google"}}}`, string(resp.Data))
+
+	resp = graphqlQuery(t, repo, `mutation($id: ID!) { updateDevice(id: $id, input: {}) { name } }`, map[string]any{"id": id})
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "BAD_USER_INPUT", resp.Errors[0].Extensions["code"])
+
+	resp = graphqlQuery(t, repo, `mutation($id: ID!) { deleteDevice(id: $id) }`, map[string]any{"id": id})
+	require.Empty(t, resp.Errors)
+	assert.Empty(t, repo.Devices)
+
+	resp = graphqlQuery(t, repo, `mutation($id: ID!) { deleteDevice(id: $id) }`, map[string]any{"id": id})
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "NOT_FOUND", resp.Errors[0].Extensions["code"])
+}
+
+func TestGraphQL_Brands(t *testing.T) {
+	repo := &countingRepository{}
+	ctx := context.Background()
+	for _, dvc := range []device.Device{
+		{Name: "Pixel 8", Brand: "Google"},
+		{Name: "iPhone 15", Brand: "Apple"},
+		{Name: "iPhone 14", Brand: "Apple"},
+	} {
+		require.NoError(t, repo.Store(ctx, &dvc))
+	}
+
+	resp := graphqlQuery(t, repo, `{ brands { name deviceCount } }`, nil)
+
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"brands": [{"name": "Apple", "deviceCount": 2}, {"name": "Google", "deviceCount": 1}]}`, string(resp.Data))
+	assert.Equal(t, int32(1), repo.countByBrand.Load())
+}
+
+func TestGraphQL_RepositoryErrorsAreInternal(t *testing.T) {
+	repo := &device.MockRepository{Err: errors.New("connection refused")}
+
+	resp := graphqlQuery(t, repo, `{ device(id: "1") { name } }`, nil)
+
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "connection refused", resp.Errors[0].Message)
+	assert.Equal(t, "INTERNAL_SERVER_ERROR", resp.Errors[0].Extensions["code"])
+}
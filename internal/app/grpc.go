@@ -0,0 +1,372 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	devicesv1 "github.com/victorspringer/1g-take-home-task/api/devices/v1"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/logging"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/watch"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// maxPageSize caps gRPC and GraphQL listing pages; larger requests are clamped.
+	maxPageSize = 1000
+
+	// watchBuffer is how many events a WatchDevices stream may fall behind
+	// before it is ended with RESOURCE_EXHAUSTED.
+	watchBuffer = 64
+)
+
+// grpcService implements devicesv1.DeviceServiceServer on top of the same
+// repository as the REST handlers.
+type grpcService struct {
+	devicesv1.UnimplementedDeviceServiceServer
+
+	logger           *zap.Logger
+	deviceRepository device.Repository
+	events           *watch.Repository
+
+	// closing is closed on shutdown to end WatchDevices streams, which would
+	// otherwise keep GracefulStop waiting.
+	closing chan struct{}
+}
+
+func newGRPCService(logger *zap.Logger, deviceRepository device.Repository, events *watch.Repository) *grpcService {
+	return &grpcService{
+		logger:           logger,
+		deviceRepository: deviceRepository,
+		events:           events,
+		closing:          make(chan struct{}),
+	}
+}
+
+// repositoryError converts a repository error to a gRPC status, logging
+// internal errors with msg.
+func (s *grpcService) repositoryError(ctx context.Context, msg string, err error) error {
+	code := grpcCode(err)
+	if code == codes.Internal {
+		logging.FromContext(ctx, s.logger).Error(msg, zap.Error(err))
+	}
+	return status.Error(code, err.Error())
+}
+
+// GetDevice returns a device by id.
+func (s *grpcService) GetDevice(ctx context.Context, req *devicesv1.GetDeviceRequest) (*devicesv1.Device, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, device.ErrMissingID.Error())
+	}
+
+	dvc, err := s.deviceRepository.FindByID(ctx, req.GetId())
+	if err != nil {
+		return nil, s.repositoryError(ctx, "error getting device by id", err)
+	}
+	if dvc == nil {
+		return nil, status.Error(codes.NotFound, device.ErrNotFound.Error())
+	}
+
+	return toProto(*dvc), nil
+}
+
+// ListDevices returns one page of devices, optionally of a single brand.
+func (s *grpcService) ListDevices(ctx context.Context, req *devicesv1.ListDevicesRequest) (*devicesv1.ListDevicesResponse, error) {
+	if req.GetPageSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	}
+	pageSize := int(req.GetPageSize())
+	if pageSize == 0 || pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	page, err := s.deviceRepository.ListPage(ctx, device.ListOptions{
+		Brand:     req.GetBrand(),
+		PageSize:  pageSize,
+		PageToken: req.GetPageToken(),
+	})
+	if err != nil {
+		return nil, s.repositoryError(ctx, "error listing devices", err)
+	}
+
+	resp := &devicesv1.ListDevicesResponse{NextPageToken: page.NextPageToken}
+	for _, dvc := range page.Devices {
+		resp.Devices = append(resp.Devices, toProto(dvc))
+	}
+	return resp, nil
+}
+
+// CreateDevice stores a new device.
+func (s *grpcService) CreateDevice(ctx context.Context, req *devicesv1.CreateDeviceRequest) (*devicesv1.Device, error) {
+	dvc := device.Device{Name: req.GetName(), Brand: req.GetBrand()}
+	if err := s.deviceRepository.Store(ctx, &dvc); err != nil {
+		return nil, s.repositoryError(ctx, "error adding device", err)
+	}
+
+	return toProto(dvc), nil
+}
+
+// UpdateDevice changes the non-empty fields of a device and returns it as stored.
+func (s *grpcService) UpdateDevice(ctx context.Context, req *devicesv1.UpdateDeviceRequest) (*devicesv1.Device, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, device.ErrMissingID.Error())
+	}
+
+	dvc := device.Device{ID: req.GetId(), Name: req.GetName(), Brand: req.GetBrand()}
+	if err := s.deviceRepository.Update(ctx, &dvc); err != nil {
+		return nil, s.repositoryError(ctx, "error updating device", err)
+	}
+
+	updated, err := s.deviceRepository.FindByID(ctx, dvc.ID)
+	if err != nil {
+		return nil, s.repositoryError(ctx, "error getting updated device", err)
+	}
+	if updated == nil {
+		return nil, status.Error(codes.NotFound, device.ErrNotFound.Error())
+	}
+
+	return toProto(*updated), nil
+}
+
+// DeleteDevice removes a device by id.
+func (s *grpcService) DeleteDevice(ctx context.Context, req *devicesv1.DeleteDeviceRequest) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, device.ErrMissingID.Error())
+	}
+
+	if err := s.deviceRepository.Remove(ctx, req.GetId()); err != nil {
+		return nil, s.repositoryError(ctx, "error deleting device", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// WatchDevices streams the changes made after the call until the client
+// cancels, the server shuts down, or the client falls too far behind.
+func (s *grpcService) WatchDevices(req *devicesv1.WatchDevicesRequest, stream grpc.ServerStreamingServer[devicesv1.DeviceEvent]) error {
+	ctx := stream.Context()
+	events := s.events.Subscribe(ctx, watchBuffer)
+
+	// Send headers right away, so clients can tell the subscription is in place.
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-s.closing:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return status.FromContextError(ctx.Err()).Err()
+				}
+				return status.Error(codes.ResourceExhausted, "watcher fell behind, list devices and watch again")
+			}
+
+			if req.GetBrand() != "" && event.Device.Brand != req.GetBrand() {
+				continue
+			}
+			if err := stream.Send(&devicesv1.DeviceEvent{
+				Type:   eventTypes[event.Type],
+				Device: toProto(event.Device),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// stop ends the open WatchDevices streams.
+func (s *grpcService) stop() {
+	close(s.closing)
+}
+
+var eventTypes = map[watch.EventType]devicesv1.DeviceEvent_Type{
+	watch.Created: devicesv1.DeviceEvent_TYPE_CREATED,
+	watch.Updated: devicesv1.DeviceEvent_TYPE_UPDATED,
+	watch.Deleted: devicesv1.DeviceEvent_TYPE_DELETED,
+}
+
+func toProto(dvc device.Device) *devicesv1.Device {
+	return &devicesv1.Device{
+		Id:           dvc.ID,
+		Name:         dvc.Name,
+		Brand:        dvc.Brand,
+		CreationTime: timestamppb.New(dvc.CreationTime),
+		UpdateTime:   timestamppb.New(dvc.UpdateTime),
+	}
+}
+
+// grpcInterceptors give RPCs what the gin middleware gives HTTP requests: a
+// request id and request-scoped logger, authentication, rate limiting, panic
+// recovery and one log line per call.
+type grpcInterceptors struct {
+	logger *zap.Logger
+	auth   *authenticator
+	limits *rateLimiter
+}
+
+func (i *grpcInterceptors) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	ctx, done, err := i.begin(ctx, info.FullMethod)
+	defer func() { done(err) }()
+	if err != nil {
+		return nil, err
+	}
+
+	defer i.recover(ctx, &err)
+	return handler(ctx, req)
+}
+
+func (i *grpcInterceptors) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	ctx, done, err := i.begin(ss.Context(), info.FullMethod)
+	defer func() { done(err) }()
+	if err != nil {
+		return err
+	}
+
+	defer i.recover(ctx, &err)
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// begin authenticates and rate limits the call and prepares its context. The
+// returned done function logs the outcome.
+func (i *grpcInterceptors) begin(ctx context.Context, method string) (context.Context, func(error), error) {
+	start := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := firstValue(md, "x-request-id")
+	if !validRequestID(requestID) {
+		requestID = uuid.New().String()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+
+	requestLogger := i.logger.With(zap.String("requestId", requestID))
+	ctx = logging.WithLogger(ctx, requestLogger)
+
+	// As over HTTP, calls failing authentication count against the quota of
+	// their IP address.
+	principal, err := i.authenticate(ctx, md)
+	if limitErr := i.rateLimit(ctx, principal); limitErr != nil {
+		err = limitErr
+	} else if err != nil {
+		err = status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	done := func(err error) {
+		fields := []zap.Field{
+			zap.String("method", method),
+			zap.String("code", status.Code(err).String()),
+			zap.Duration("latency", time.Since(start)),
+		}
+		if principal != "" {
+			fields = append(fields, zap.String("principal", principal))
+		}
+		requestLogger.Info("rpc", fields...)
+	}
+
+	return ctx, done, err
+}
+
+// authenticate accepts a client certificate verified by mutual TLS, or an API
+// key sent as x-api-key or "authorization: Bearer" metadata.
+func (i *grpcInterceptors) authenticate(ctx context.Context, md metadata.MD) (string, error) {
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			if principal, ok := certificatePrincipal(&info.State); ok {
+				return principal, nil
+			}
+		}
+	}
+
+	return i.auth.authenticate(bearerKey(firstValue(md, "x-api-key"), firstValue(md, "authorization")))
+}
+
+// rateLimit counts the call against the caller's default quota, shared with
+// the REST API, and once it is spent returns a RESOURCE_EXHAUSTED status with
+// the seconds to wait in retry-after metadata. Per-route limits only apply to
+// REST routes.
+func (i *grpcInterceptors) rateLimit(ctx context.Context, principal string) error {
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+
+	_, result, checked := i.limits.check(ctx, clientKey(principal, ip), "")
+	if !checked || result.Allowed {
+		return nil
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds(result.RetryAfter))))
+	return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+}
+
+// recover turns a handler panic into an INTERNAL status.
+func (i *grpcInterceptors) recover(ctx context.Context, err *error) {
+	if r := recover(); r != nil {
+		logging.FromContext(ctx, i.logger).Error("panic serving rpc", zap.Any("panic", r), zap.Stack("stack"))
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
+
+// contextStream overrides the context of a server stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// newGRPCServer builds a server with the interceptors and service registered.
+func newGRPCServer(service *grpcService, interceptors *grpcInterceptors, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(interceptors.unary),
+		grpc.ChainStreamInterceptor(interceptors.stream),
+	)
+	srv := grpc.NewServer(opts...)
+	devicesv1.RegisterDeviceServiceServer(srv, service)
+	return srv
+}
+
+// stopGRPC ends the watch streams and drains in-flight calls, cutting them
+// off if ctx is done first.
+func stopGRPC(ctx context.Context, srv *grpc.Server, service *grpcService) error {
+	service.stop()
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		srv.Stop()
+		return errors.New("grpc server did not drain before the shutdown deadline")
+	}
+}
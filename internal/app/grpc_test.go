@@ -0,0 +1,232 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	devicesv1 "github.com/victorspringer/1g-take-home-task/api/devices/v1"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/watch"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// setupGRPC serves repo over an in-memory connection and returns a client.
+func setupGRPC(t *testing.T, repo device.Repository, principals map[string]string) devicesv1.DeviceServiceClient {
+	return setupRateLimitedGRPC(t, repo, principals, config.Default().RateLimit)
+}
+
+// setupRateLimitedGRPC is setupGRPC with the rate limits of cfg.
+func setupRateLimitedGRPC(t *testing.T, repo device.Repository, principals map[string]string, cfg config.RateLimit) devicesv1.DeviceServiceClient {
+	t.Helper()
+
+	events := watch.New(repo)
+	service := newGRPCService(zap.NewNop(), events, events)
+	srv := newGRPCServer(service, &grpcInterceptors{
+		logger: zap.NewNop(),
+		auth:   newAuthenticator(principals),
+		limits: newRateLimiter(zap.NewNop(), ratelimit.NewMemoryStore(), cfg),
+	})
+
+	listener := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(listener) }()
+	t.Cleanup(func() {
+		_ = stopGRPC(context.Background(), srv, service)
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return devicesv1.NewDeviceServiceClient(conn)
+}
+
+func TestGRPC_GetDevice(t *testing.T) {
+	repo := &device.MockRepository{}
+	client := setupGRPC(t, repo, nil)
+	ctx := context.Background()
+
+	dvc := &device.Device{Name: "Pixel 8", Brand: "Google"}
+	require.NoError(t, repo.Store(ctx, dvc))
+
+	got, err := client.GetDevice(ctx, &devicesv1.GetDeviceRequest{Id: dvc.ID})
+	require.NoError(t, err)
+	assert.Equal(t, dvc.ID, got.GetId())
+	assert.Equal(t, "Pixel 8", got.GetName())
+	assert.True(t, dvc.CreationTime.Equal(got.GetCreationTime().AsTime()))
+
+	_, err = client.GetDevice(ctx, &devicesv1.GetDeviceRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.GetDevice(ctx, &devicesv1.GetDeviceRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPC_ListDevicesPaginates(t *testing.T) {
+	repo := &device.MockRepository{}
+	client := setupGRPC(t, repo, nil)
+	ctx := context.Background()
+
+	for _, name := range []string{"Pixel 7", "Galaxy S24", "Pixel 8"} {
+		brand := "Google"
+		if name == "Galaxy S24" {
+			brand = "Samsung"
+		}
+		require.NoError(t, repo.Store(ctx, &device.Device{Name: name, Brand: brand}))
+	}
+
+	first, err := client.ListDevices(ctx, &devicesv1.ListDevicesRequest{PageSize: 1, Brand: "Google"})
+	require.NoError(t, err)
+	require.Len(t, first.GetDevices(), 1)
+	assert.Equal(t, "Pixel 7", first.GetDevices()[0].GetName())
+	require.NotEmpty(t, first.GetNextPageToken())
+
+	second, err := client.ListDevices(ctx, &devicesv1.ListDevicesRequest{PageSize: 1, Brand: "Google", PageToken: first.GetNextPageToken()})
+	require.NoError(t, err)
+	require.Len(t, second.GetDevices(), 1)
+	assert.Equal(t, "Pixel 8", second.GetDevices()[0].GetName())
+	assert.Empty(t, second.GetNextPageToken())
+
+	_, err = client.ListDevices(ctx, &devicesv1.ListDevicesRequest{PageToken: "not a token"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.ListDevices(ctx, &devicesv1.ListDevicesRequest{PageSize: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPC_CreateUpdateDelete(t *testing.T) {
+	client := setupGRPC(t, &device.MockRepository{}, nil)
+	ctx := context.Background()
+
+	created, err := client.CreateDevice(ctx, &devicesv1.CreateDeviceRequest{Name: "Pixel 8", Brand: "Google"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.GetId())
+
+	updated, err := client.UpdateDevice(ctx, &devicesv1.UpdateDeviceRequest{Id: created.GetId(), Name: "Pixel 8 Pro"})
+	require.NoError(t, err)
+	assert.Equal(t, "Pixel 8 Pro", updated.GetName())
+	assert.Equal(t, "Google", updated.GetBrand())
+
+	_, err = client.UpdateDevice(ctx, &devicesv1.UpdateDeviceRequest{Id: created.GetId()})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.DeleteDevice(ctx, &devicesv1.DeleteDeviceRequest{Id: created.GetId()})
+	require.NoError(t, err)
+
+	_, err = client.DeleteDevice(ctx, &devicesv1.DeleteDeviceRequest{Id: created.GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// failingRepository fails every lookup by id.
+type failingRepository struct {
+	device.MockRepository
+}
+
+func (*failingRepository) FindByID(context.Context, string) (*device.Device, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestGRPC_RepositoryErrorsAreInternal(t *testing.T) {
+	client := setupGRPC(t, &failingRepository{}, nil)
+
+	_, err := client.GetDevice(context.Background(), &devicesv1.GetDeviceRequest{Id: "1"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestGRPC_WatchDevices(t *testing.T) {
+	client := setupGRPC(t, &device.MockRepository{}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchDevices(ctx, &devicesv1.WatchDevicesRequest{Brand: "Google"})
+	require.NoError(t, err)
+	// Headers are sent once the subscription is in place.
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	_, err = client.CreateDevice(ctx, &devicesv1.CreateDeviceRequest{Name: "Galaxy S24", Brand: "Samsung"})
+	require.NoError(t, err)
+	created, err := client.CreateDevice(ctx, &devicesv1.CreateDeviceRequest{Name: "Pixel 8", Brand: "Google"})
+	require.NoError(t, err)
+	_, err = client.DeleteDevice(ctx, &devicesv1.DeleteDeviceRequest{Id: created.GetId()})
+	require.NoError(t, err)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, devicesv1.DeviceEvent_TYPE_CREATED, event.GetType())
+	assert.Equal(t, "Pixel 8", event.GetDevice().GetName(), "other brands are filtered out")
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, devicesv1.DeviceEvent_TYPE_DELETED, event.GetType())
+	assert.Equal(t, created.GetId(), event.GetDevice().GetId())
+}
+
+func TestGRPC_Authentication(t *testing.T) {
+	client := setupGRPC(t, &device.MockRepository{}, map[string]string{"k1": "ci"})
+	ctx := context.Background()
+
+	_, err := client.ListDevices(ctx, &devicesv1.ListDevicesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.ListDevices(metadata.AppendToOutgoingContext(ctx, "x-api-key", "k2"), &devicesv1.ListDevicesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.ListDevices(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer k1"), &devicesv1.ListDevicesRequest{})
+	assert.NoError(t, err)
+
+	stream, err := client.WatchDevices(ctx, &devicesv1.WatchDevicesRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPC_RateLimit(t *testing.T) {
+	client := setupRateLimitedGRPC(t, &device.MockRepository{}, map[string]string{"k1": "ci", "k2": "ops"}, config.RateLimit{
+		Requests: 2,
+		Period:   time.Minute,
+	})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "k1")
+
+	for i := 0; i < 2; i++ {
+		_, err := client.ListDevices(ctx, &devicesv1.ListDevicesRequest{})
+		require.NoError(t, err)
+	}
+
+	var header metadata.MD
+	_, err := client.ListDevices(ctx, &devicesv1.ListDevicesRequest{}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"30"}, header.Get("retry-after"))
+
+	stream, err := client.WatchDevices(ctx, &devicesv1.WatchDevicesRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "streams count too")
+
+	_, err = client.ListDevices(metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "k2"), &devicesv1.ListDevicesRequest{})
+	assert.NoError(t, err, "each principal has its own quota")
+
+	for i := 0; i < 2; i++ {
+		_, err = client.ListDevices(context.Background(), &devicesv1.ListDevicesRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+	_, err = client.ListDevices(context.Background(), &devicesv1.ListDevicesRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "failed authentication counts against the peer's quota")
+}
@@ -2,163 +2,377 @@ package app
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/logging"
 	"go.uber.org/zap"
 )
 
+const (
+	// defaultSearchLimit and maxSearchLimit bound free-text search results.
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+
+	// defaultHistogramBuckets is how many intervals the creation histogram
+	// covers without a from parameter; maxHistogramBuckets bounds any range.
+	defaultHistogramBuckets = 30
+	maxHistogramBuckets     = 1000
+
+	// maxBatchSize caps the ids of a batch request.
+	maxBatchSize = 1000
+)
+
 type handler struct {
 	logger           *zap.Logger
 	deviceRepository device.Repository
 }
 
+// log returns the request-scoped logger, which carries the request and trace ids.
+func (h *handler) log(c *gin.Context) *zap.Logger {
+	return logging.FromContext(c.Request.Context(), h.logger)
+}
+
+// repositoryError answers with the status errorCodes maps err to, logging
+// internal errors with msg.
+func (h *handler) repositoryError(c *gin.Context, msg string, err error) {
+	switch status := httpStatus(err); status {
+	case http.StatusNotFound:
+		respond(c, status, gin.H{
+			"status": http.StatusText(status),
+		})
+	case http.StatusInternalServerError:
+		h.log(c).Error(msg, zap.Error(err))
+		respond(c, status, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		respond(c, status, gin.H{
+			"error": err.Error(),
+		})
+	}
+}
+
 func (h *handler) healthCheck(c *gin.Context) {
-	h.logger.Debug("health check", zap.String("requestUrl", c.Request.URL.Path))
-	c.JSON(http.StatusOK, gin.H{
+	respond(c, http.StatusOK, gin.H{
 		"status": http.StatusText(http.StatusOK),
 	})
 }
 
 // @Summary List all devices
-// @Description Get a list of all devices
+// @Description Get a list of all devices, ordered by creation time. With pageSize the list is paginated: pass the returned nextPageToken as pageToken to get the following page.
 // @ID list-all-devices
-// @Produce json
+// @Param pageSize query int false "Devices per page, up to 1000"
+// @Param pageToken query string false "Token of the page to get"
+// @Param fields query string false "Comma-separated device fields to return: id, name, brand, creationTime, updateTime"
+// @Param expand query string false "Comma-separated relations to add under _expanded: brand"
+// @Produce json,application/msgpack,application/cbor
+// @Security ApiKeyAuth
+// @Param If-None-Match header string false "ETag of a copy the client holds"
 // @Success 200
+// @Success 304
+// @Failure 400
+// @Failure 401
 // @Failure 404
+// @Failure 429
 // @Failure 500
 // @Router /devices [get]
 func (h *handler) listAllDevices(c *gin.Context) {
-	h.logger.Debug("list all devices", zap.String("requestUrl", c.Request.URL.Path))
+	v, ok := parseView(c)
+	if !ok {
+		return
+	}
 
-	devices, err := h.deviceRepository.List()
+	if c.Query("pageSize") != "" || c.Query("pageToken") != "" {
+		h.listDevicesPage(c, v)
+		return
+	}
+
+	var (
+		devices []device.Device
+		err     error
+	)
+	if len(v.fields) > 0 {
+		// ListPage leaves the columns not asked for out of the query.
+		var page device.Page
+		page, err = h.deviceRepository.ListPage(c.Request.Context(), device.ListOptions{Fields: v.selects()})
+		devices = page.Devices
+	} else {
+		devices, err = h.deviceRepository.List(c.Request.Context())
+	}
 	if err != nil {
-		h.logger.Error("error listing all devices", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
+		h.log(c).Error("error listing all devices", zap.Error(err))
+		respond(c, http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
 	if len(devices) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
+		respond(c, http.StatusNotFound, gin.H{
 			"status": http.StatusText(http.StatusNotFound),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"devices": devices,
+	if err := v.load(c.Request.Context(), h.deviceRepository, devices...); err != nil {
+		h.repositoryError(c, "error loading expanded relations", err)
+		return
+	}
+
+	if notModified(c, v.validator(), devices...) {
+		return
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"devices": v.devices(devices),
 	})
 }
 
+// listDevicesPage answers a paginated listing. A missing pageSize defaults to
+// the largest page.
+func (h *handler) listDevicesPage(c *gin.Context, v view) {
+	pageSize := maxPageSize
+	if value := c.Query("pageSize"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			respond(c, http.StatusBadRequest, gin.H{
+				"error": "pageSize must be a positive integer",
+			})
+			return
+		}
+		pageSize = min(size, maxPageSize)
+	}
+
+	page, err := h.deviceRepository.ListPage(c.Request.Context(), device.ListOptions{
+		PageSize:  pageSize,
+		PageToken: c.Query("pageToken"),
+		Fields:    v.selects(),
+	})
+	if err != nil {
+		h.repositoryError(c, "error listing devices", err)
+		return
+	}
+
+	if len(page.Devices) == 0 {
+		respond(c, http.StatusNotFound, gin.H{
+			"status": http.StatusText(http.StatusNotFound),
+		})
+		return
+	}
+
+	if err := v.load(c.Request.Context(), h.deviceRepository, page.Devices...); err != nil {
+		h.repositoryError(c, "error loading expanded relations", err)
+		return
+	}
+
+	if notModified(c, page.NextPageToken+"\n"+v.validator(), page.Devices...) {
+		return
+	}
+
+	response := gin.H{
+		"devices": v.devices(page.Devices),
+	}
+	if page.NextPageToken != "" {
+		response["nextPageToken"] = page.NextPageToken
+	}
+	respond(c, http.StatusOK, response)
+}
+
 // @Summary Get device by id
 // @Description Get device data by id
 // @ID get-device-by-id
 // @Param id path string true "Device's ID"
-// @Produce json
+// @Param fields query string false "Comma-separated device fields to return: id, name, brand, creationTime, updateTime"
+// @Param expand query string false "Comma-separated relations to add under _expanded: brand"
+// @Produce json,application/msgpack,application/cbor
+// @Security ApiKeyAuth
+// @Param If-None-Match header string false "ETag of a copy the client holds"
+// @Param If-Modified-Since header string false "Last-Modified of a copy the client holds"
 // @Success 200
+// @Success 304
+// @Failure 400
+// @Failure 401
 // @Failure 404
+// @Failure 429
 // @Failure 500
 // @Router /devices/{id} [get]
 func (h *handler) getDeviceByID(c *gin.Context) {
-	h.logger.Debug("get device by id", zap.String("requestUrl", c.Request.URL.Path))
+	v, ok := parseView(c)
+	if !ok {
+		return
+	}
 
 	id := c.Param("id")
-	device, err := h.deviceRepository.FindByID(id)
+	device, err := h.deviceRepository.FindByID(c.Request.Context(), id)
 	if err != nil {
-		h.logger.Error("error getting device by id", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
+		h.log(c).Error("error getting device by id", zap.Error(err))
+		respond(c, http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
 	if device == nil {
-		c.JSON(http.StatusNotFound, gin.H{
+		respond(c, http.StatusNotFound, gin.H{
 			"status": http.StatusText(http.StatusNotFound),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"device": device,
+	if err := v.load(c.Request.Context(), h.deviceRepository, *device); err != nil {
+		h.repositoryError(c, "error loading expanded relations", err)
+		return
+	}
+
+	if deviceNotModified(c, v.validator(), *device) {
+		return
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"device": v.device(*device),
 	})
 }
 
-// @Summary Get devices by brand
-// @Description Get a list of device data by brand
+// @Summary Search devices
+// @Description Get a list of device data by brand, or, with q, devices whose name or brand match the words typed, best first.
+// @Description Words match as prefixes ("galaxy s2" finds "Galaxy S23") and misspelled ones by similarity; highlights mark the matching words with <mark> tags.
 // @ID search-devices
-// @Param brand query string true "Device's brand"
-// @Produce json
+// @Param brand query string false "Device's brand"
+// @Param q query string false "Free-text query"
+// @Param limit query int false "Maximum number of results with q (default 20, at most 100)"
+// @Param fields query string false "Comma-separated device fields to return with brand: id, name, brand, creationTime, updateTime"
+// @Param expand query string false "Comma-separated relations to add under _expanded: brand"
+// @Produce json,application/msgpack,application/cbor
+// @Security ApiKeyAuth
 // @Success 200
+// @Failure 400
+// @Failure 401
 // @Failure 404
+// @Failure 429
 // @Failure 500
 // @Router /devices/search [get]
 func (h *handler) searchDevices(c *gin.Context) {
-	h.logger.Debug("search device", zap.String("requestUrl", c.Request.URL.Path+"?"+c.Request.URL.Query().Encode()))
+	if query := c.Query("q"); query != "" {
+		h.searchDevicesText(c, query)
+		return
+	}
+
+	v, ok := parseView(c)
+	if !ok {
+		return
+	}
 
 	brand := c.Query("brand")
 
-	devices, err := h.deviceRepository.FindByBrand(brand)
+	devices, err := h.deviceRepository.FindByBrand(c.Request.Context(), brand)
 	if err != nil {
-		h.logger.Error("error searching devices by brand", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
+		h.log(c).Error("error searching devices by brand", zap.Error(err))
+		respond(c, http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
 	if len(devices) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
+		respond(c, http.StatusNotFound, gin.H{
+			"status": http.StatusText(http.StatusNotFound),
+		})
+		return
+	}
+
+	if err := v.load(c.Request.Context(), h.deviceRepository, devices...); err != nil {
+		h.repositoryError(c, "error loading expanded relations", err)
+		return
+	}
+
+	if notModified(c, v.validator(), devices...) {
+		return
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"devices": v.devices(devices),
+	})
+}
+
+// searchDevicesText serves /devices/search?q=.
+func (h *handler) searchDevicesText(c *gin.Context, query string) {
+	limit := defaultSearchLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			respond(c, http.StatusBadRequest, gin.H{
+				"error": "limit must be a positive integer",
+			})
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	results, err := h.deviceRepository.Search(c.Request.Context(), query, limit)
+	if err != nil {
+		h.log(c).Error("error searching devices", zap.Error(err))
+		respond(c, http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if len(results) == 0 {
+		respond(c, http.StatusNotFound, gin.H{
 			"status": http.StatusText(http.StatusNotFound),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"devices": devices,
+	respond(c, http.StatusOK, gin.H{
+		"results": results,
 	})
 }
 
 // @Summary Add device
-// @Description Creates a new device
+// @Description Creates a new device and returns it with its id and timestamps
 // @ID add-device
 // @Param device body device.Device true "Device to add"
-// @Produce json
+// @Produce json,application/msgpack,application/cbor
+// @Security ApiKeyAuth
 // @Success 201
 // @Failure 400
+// @Failure 401
+// @Failure 413
+// @Failure 429
 // @Failure 500
 // @Router /devices [post]
 func (h *handler) addDevice(c *gin.Context) {
-	h.logger.Debug("add device")
-
 	var dvc device.Device
 
-	if err := c.ShouldBindJSON(&dvc); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	if !bindBody(c, &dvc) {
 		return
 	}
 
 	if dvc.ID != "" {
-		c.JSON(http.StatusBadRequest, gin.H{
+		respond(c, http.StatusBadRequest, gin.H{
 			"error": "device id is not a valid field",
 		})
 		return
 	}
 
-	if err := h.deviceRepository.Store(&dvc); err != nil {
-		h.logger.Error("error adding device", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
+	if err := h.deviceRepository.Store(c.Request.Context(), &dvc); err != nil {
+		h.log(c).Error("error adding device", zap.Error(err))
+		respond(c, http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	// Relative to the request, so it keeps the version prefix, if any.
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+dvc.ID)
+	respond(c, http.StatusCreated, gin.H{
 		"status": http.StatusText(http.StatusCreated),
+		"device": dvc,
 	})
 }
 
@@ -167,36 +381,33 @@ func (h *handler) addDevice(c *gin.Context) {
 // @ID update-device
 // @Param id path string true "Device's ID"
 // @Param device body device.Device true "Fields to update"
-// @Produce json
+// @Produce json,application/msgpack,application/cbor
+// @Security ApiKeyAuth
 // @Success 200
 // @Failure 400
+// @Failure 401
+// @Failure 404
+// @Failure 413
+// @Failure 429
 // @Failure 500
 // @Router /devices/{id} [patch]
 func (h *handler) updateDevice(c *gin.Context) {
-	h.logger.Debug("update device", zap.String("requestUrl", c.Request.URL.Path))
-
 	id := c.Param("id")
 
 	var dvc device.Device
 
-	if err := c.ShouldBindJSON(&dvc); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	if !bindBody(c, &dvc) {
 		return
 	}
 
 	dvc.ID = id
 
-	if err := h.deviceRepository.Update(&dvc); err != nil {
-		h.logger.Error("error updating device", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+	if err := h.deviceRepository.Update(c.Request.Context(), &dvc); err != nil {
+		h.repositoryError(c, "error updating device", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	respond(c, http.StatusOK, gin.H{
 		"status": http.StatusText(http.StatusOK),
 	})
 }
@@ -205,24 +416,233 @@ func (h *handler) updateDevice(c *gin.Context) {
 // @Description Delete device data by id
 // @ID delete-device
 // @Param id path string true "Device's ID"
-// @Produce json
+// @Produce json,application/msgpack,application/cbor
+// @Security ApiKeyAuth
 // @Success 200
+// @Failure 401
+// @Failure 404
+// @Failure 429
 // @Failure 500
 // @Router /devices/{id} [delete]
 func (h *handler) deleteDevice(c *gin.Context) {
-	h.logger.Debug("delete device", zap.String("requestUrl", c.Request.URL.Path))
-
 	id := c.Param("id")
 
-	if err := h.deviceRepository.Remove(id); err != nil {
-		h.logger.Error("error deleting device", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
+	if err := h.deviceRepository.Remove(c.Request.Context(), id); err != nil {
+		h.repositoryError(c, "error deleting device", err)
+		return
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"status": http.StatusText(http.StatusOK),
+	})
+}
+
+// batchRequest is the body of the batch endpoints.
+type batchRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// batchResult reports the outcome for one id of a batch request: status is
+// "OK" with the device, or "Not Found".
+type batchResult struct {
+	ID     string         `json:"id"`
+	Status string         `json:"status"`
+	Device *device.Device `json:"device,omitempty"`
+}
+
+// batchActions are the custom methods served as POST /devices:<action>,
+// keyed by the ":<action>" parameter gin routes after /devices.
+var batchActions = map[string]func(*handler, *gin.Context){
+	":batchGet":    (*handler).batchGetDevices,
+	":batchDelete": (*handler).batchDeleteDevices,
+}
+
+// batchAction serves POST /devices:<action> once knownAction has checked
+// the action.
+func (h *handler) batchAction(c *gin.Context) {
+	batchActions[c.Param("action")](h, c)
+}
+
+// bindBatch reads the ids of a batch request, answering with 400 when there
+// are none or more than maxBatchSize.
+func bindBatch(c *gin.Context) ([]string, bool) {
+	var req batchRequest
+	if !bindBody(c, &req) {
+		return nil, false
+	}
+
+	if len(req.IDs) == 0 || len(req.IDs) > maxBatchSize {
+		respond(c, http.StatusBadRequest, gin.H{
+			"error": "ids must hold between 1 and " + strconv.Itoa(maxBatchSize) + " device ids",
+		})
+		return nil, false
+	}
+	return req.IDs, true
+}
+
+// batchResults lists a result per requested id, in request order.
+func batchResults(ids []string, devices []device.Device, includeDevices bool) []batchResult {
+	found := make(map[string]*device.Device, len(devices))
+	for i := range devices {
+		found[devices[i].ID] = &devices[i]
+	}
+
+	results := make([]batchResult, len(ids))
+	for i, id := range ids {
+		results[i] = batchResult{ID: id, Status: http.StatusText(http.StatusNotFound)}
+		if dvc, ok := found[id]; ok {
+			results[i].Status = http.StatusText(http.StatusOK)
+			if includeDevices {
+				results[i].Device = dvc
+			}
+		}
+	}
+	return results
+}
+
+// @Summary Get devices by IDs
+// @Description Get up to 1000 devices in one request. Results follow the order of ids, with a "Not Found" status for missing devices.
+// @ID batch-get-devices
+// @Param ids body batchRequest true "Device IDs"
+// @Accept json,application/msgpack,application/cbor
+// @Produce json,application/msgpack,application/cbor
+// @Security ApiKeyAuth
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 413
+// @Failure 429
+// @Failure 500
+// @Router /devices:batchGet [post]
+func (h *handler) batchGetDevices(c *gin.Context) {
+	ids, ok := bindBatch(c)
+	if !ok {
+		return
+	}
+
+	devices, err := h.deviceRepository.FindByIDs(c.Request.Context(), ids)
+	if err != nil {
+		h.repositoryError(c, "error getting devices by ids", err)
+		return
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"results": batchResults(ids, devices, true),
+	})
+}
+
+// @Summary Delete devices by IDs
+// @Description Delete up to 1000 devices in one request. Results follow the order of ids, with a "Not Found" status for devices that did not exist.
+// @ID batch-delete-devices
+// @Param ids body batchRequest true "Device IDs"
+// @Accept json,application/msgpack,application/cbor
+// @Produce json,application/msgpack,application/cbor
+// @Security ApiKeyAuth
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 413
+// @Failure 429
+// @Failure 500
+// @Router /devices:batchDelete [post]
+func (h *handler) batchDeleteDevices(c *gin.Context) {
+	ids, ok := bindBatch(c)
+	if !ok {
+		return
+	}
+
+	removed, err := h.deviceRepository.RemoveByIDs(c.Request.Context(), ids)
+	if err != nil {
+		h.repositoryError(c, "error deleting devices by ids", err)
+		return
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"results": batchResults(ids, removed, false),
+	})
+}
+
+// @Summary Get device statistics
+// @Description Get the number of devices, in total and per brand, and a histogram of the devices created per day, week or month in [from, to).
+// @Description Buckets start at midnight UTC, weeks on Monday; every interval in the range has a bucket, empty ones included.
+// @ID device-stats
+// @Param interval query string false "Histogram bucket width: day (default), week or month"
+// @Param from query string false "Start of the histogram, RFC 3339 (default 30 intervals before to)"
+// @Param to query string false "End of the histogram, RFC 3339, exclusive (default now)"
+// @Produce json,application/msgpack,application/cbor
+// @Security ApiKeyAuth
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 429
+// @Failure 500
+// @Router /devices/stats [get]
+func (h *handler) deviceStats(c *gin.Context) {
+	interval, err := device.ParseInterval(c.DefaultQuery("interval", string(device.Day)))
+	if err != nil {
+		respond(c, http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": http.StatusText(http.StatusOK),
+	to := time.Now().UTC()
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.Parse(time.RFC3339, raw); err != nil {
+			respond(c, http.StatusBadRequest, gin.H{
+				"error": "to must be an RFC 3339 time",
+			})
+			return
+		}
+	}
+	from := interval.Add(interval.Truncate(to), 1-defaultHistogramBuckets)
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.Parse(time.RFC3339, raw); err != nil {
+			respond(c, http.StatusBadRequest, gin.H{
+				"error": "from must be an RFC 3339 time",
+			})
+			return
+		}
+	}
+	if !from.Before(to) {
+		respond(c, http.StatusBadRequest, gin.H{
+			"error": "from must be before to",
+		})
+		return
+	}
+	if interval.Add(interval.Truncate(from), maxHistogramBuckets).Before(to) {
+		respond(c, http.StatusBadRequest, gin.H{
+			"error": "range spans more than " + strconv.Itoa(maxHistogramBuckets) + " intervals",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	counts, err := h.deviceRepository.CountByBrand(ctx)
+	if err != nil {
+		h.repositoryError(c, "error counting devices by brand", err)
+		return
+	}
+	options := device.HistogramOptions{Interval: interval, From: from, To: to}
+	buckets, err := h.deviceRepository.CreationHistogram(ctx, options)
+	if err != nil {
+		h.repositoryError(c, "error computing creation histogram", err)
+		return
+	}
+
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+
+	respond(c, http.StatusOK, gin.H{
+		"total":  total,
+		"brands": counts,
+		"creations": gin.H{
+			"interval": interval,
+			"from":     from,
+			"to":       to,
+			"buckets":  buckets,
+		},
 	})
 }
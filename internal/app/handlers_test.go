@@ -2,34 +2,31 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
 	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
 	"go.uber.org/zap"
 )
 
-func setupRouter(repo device.Repository) *gin.Engine {
+// setupRouter returns the production router over repo, so handlers are
+// tested on the routes and middleware they are served with.
+func setupRouter(repo device.Repository) http.Handler {
 	gin.SetMode(gin.TestMode)
-	router := gin.Default()
-	h := &handler{
-		logger:           zap.NewNop(),
-		deviceRepository: repo,
-	}
-
-	router.GET("/devices", h.listAllDevices)
-	router.GET("/devices/:id", h.getDeviceByID)
-	router.GET("/devices/search", h.searchDevices)
-	router.POST("/devices", h.addDevice)
-	router.PATCH("/devices/:id", h.updateDevice)
-	router.DELETE("/devices/:id", h.deleteDevice)
-
-	return router
+	return NewRouter(config.Default(), Options{
+		Logger:   zap.NewNop(),
+		Devices:  repo,
+		Registry: prometheus.NewRegistry(),
+	})
 }
 
 func TestListAllDevices_Success(t *testing.T) {
@@ -75,6 +72,47 @@ func TestListAllDevices_Error(t *testing.T) {
 	assert.JSONEq(t, `{"error": "internal error"}`, w.Body.String())
 }
 
+func TestListAllDevices_Paginated(t *testing.T) {
+	repo := &device.MockRepository{}
+	for _, name := range []string{"Device1", "Device2", "Device3"} {
+		_ = repo.Store(context.Background(), &device.Device{Name: name, Brand: "BrandA"})
+	}
+	router := setupRouter(repo)
+
+	var first struct {
+		Devices       []device.Device `json:"devices"`
+		NextPageToken string          `json:"nextPageToken"`
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/devices?pageSize=2", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+	assert.Len(t, first.Devices, 2)
+	assert.NotEmpty(t, first.NextPageToken)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/devices?pageSize=2&pageToken="+first.NextPageToken, nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	expectedResponse, _ := json.Marshal(gin.H{"devices": repo.Devices[2:]})
+	assert.JSONEq(t, string(expectedResponse), w.Body.String())
+}
+
+func TestListAllDevices_InvalidPagination(t *testing.T) {
+	router := setupRouter(&device.MockRepository{})
+
+	for _, query := range []string{"pageSize=0", "pageSize=ten", "pageToken=bogus!"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/devices?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
 func TestGetDeviceByID_Success(t *testing.T) {
 	repo := &device.MockRepository{
 		Devices: []device.Device{
@@ -165,6 +203,94 @@ func TestSearchDevices_Error(t *testing.T) {
 	assert.JSONEq(t, `{"error": "internal error"}`, w.Body.String())
 }
 
+func TestSearchDevices_Text(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "iPhone 15", Brand: "Apple"},
+			{ID: "2", Name: "Galaxy S23", Brand: "Samsung"},
+			{ID: "3", Name: "Galaxy Tab S9", Brand: "Samsung"},
+		},
+	}
+	router := setupRouter(repo)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/devices/search?q=galaxy+s2&limit=1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Results []device.SearchResult `json:"results"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	if assert.Len(t, response.Results, 1) {
+		assert.Equal(t, "2", response.Results[0].Device.ID)
+		assert.Equal(t, "<mark>Galaxy</mark> <mark>S23</mark>", response.Results[0].Highlights.Name)
+	}
+}
+
+func TestSearchDevices_TextErrors(t *testing.T) {
+	router := setupRouter(&device.MockRepository{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/devices/search?q=galaxy&limit=0", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/devices/search?q=galaxy", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeviceStats_Success(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "iPhone 15", Brand: "Apple", CreationTime: day(1)},
+			{ID: "2", Name: "Pixel 8", Brand: "Google", CreationTime: day(5)},
+			{ID: "3", Name: "iPhone 14", Brand: "Apple", CreationTime: day(6)},
+			{ID: "4", Name: "Pixel 9", Brand: "Google", CreationTime: day(20)},
+		},
+	}
+	router := setupRouter(repo)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/devices/stats?interval=week&from=2024-03-01T00:00:00Z&to=2024-03-18T00:00:00Z", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"total": 4,
+		"brands": {"Apple": 2, "Google": 2},
+		"creations": {
+			"interval": "week",
+			"from": "2024-03-01T00:00:00Z",
+			"to": "2024-03-18T00:00:00Z",
+			"buckets": [
+				{"start": "2024-02-26T00:00:00Z", "count": 1},
+				{"start": "2024-03-04T00:00:00Z", "count": 2},
+				{"start": "2024-03-11T00:00:00Z", "count": 0}
+			]
+		}
+	}`, w.Body.String())
+}
+
+func TestDeviceStats_InvalidParameters(t *testing.T) {
+	router := setupRouter(&device.MockRepository{})
+
+	for _, query := range []string{
+		"interval=year",
+		"from=yesterday",
+		"from=2024-03-02T00:00:00Z&to=2024-03-01T00:00:00Z",
+		"interval=day&from=2000-01-01T00:00:00Z&to=2024-01-01T00:00:00Z",
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/devices/stats?"+query, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
 func TestAddDevice_Success(t *testing.T) {
 	repo := &device.MockRepository{}
 	router := setupRouter(repo)
@@ -178,7 +304,9 @@ func TestAddDevice_Success(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusCreated, w.Code)
-	assert.JSONEq(t, `{"status": "Created"}`, w.Body.String())
+	assert.Equal(t, "/devices/"+repo.Devices[0].ID, w.Header().Get("Location"))
+	expectedResponse, _ := json.Marshal(gin.H{"status": "Created", "device": repo.Devices[0]})
+	assert.JSONEq(t, string(expectedResponse), w.Body.String())
 }
 
 func TestAddDevice_BadRequest(t *testing.T) {
@@ -232,6 +360,39 @@ func TestUpdateDevice_Success(t *testing.T) {
 	assert.JSONEq(t, `{"status": "OK"}`, w.Body.String())
 }
 
+func TestUpdateDevice_NotFound(t *testing.T) {
+	repo := &device.MockRepository{}
+	router := setupRouter(repo)
+
+	updatedDevice := device.Device{Name: "UpdatedDevice"}
+	jsonDevice, _ := json.Marshal(updatedDevice)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/devices/1", bytes.NewBuffer(jsonDevice))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"status": "Not Found"}`, w.Body.String())
+}
+
+func TestUpdateDevice_NoFields(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+		},
+	}
+	router := setupRouter(repo)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/devices/1", bytes.NewBuffer([]byte("{}")))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "invalid update request"}`, w.Body.String())
+}
+
 func TestUpdateDevice_Error(t *testing.T) {
 	repo := &device.MockRepository{
 		Err: errors.New("internal error"),
@@ -265,6 +426,18 @@ func TestDeleteDevice_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestDeleteDevice_NotFound(t *testing.T) {
+	repo := &device.MockRepository{}
+	router := setupRouter(repo)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/devices/1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"status": "Not Found"}`, w.Body.String())
+}
+
 func TestDeleteDevice_Error(t *testing.T) {
 	repo := &device.MockRepository{
 		Err: errors.New("internal error"),
@@ -278,3 +451,69 @@ func TestDeleteDevice_Error(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.JSONEq(t, `{"error": "internal error"}`, w.Body.String())
 }
+
+func TestBatchGetDevices(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+			{ID: "2", Name: "Device2", Brand: "BrandB"},
+		},
+	}
+	router := setupRouter(repo)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/devices:batchGet", bytes.NewBufferString(`{"ids": ["2", "missing", "1"]}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	expectedResponse, _ := json.Marshal(gin.H{"results": []gin.H{
+		{"id": "2", "status": "OK", "device": repo.Devices[1]},
+		{"id": "missing", "status": "Not Found"},
+		{"id": "1", "status": "OK", "device": repo.Devices[0]},
+	}})
+	assert.JSONEq(t, string(expectedResponse), w.Body.String())
+}
+
+func TestBatchDeleteDevices(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+			{ID: "2", Name: "Device2", Brand: "BrandB"},
+		},
+	}
+	router := setupRouter(repo)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/devices:batchDelete", bytes.NewBufferString(`{"ids": ["1", "missing"]}`))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"results": [{"id": "1", "status": "OK"}, {"id": "missing", "status": "Not Found"}]}`, w.Body.String())
+	assert.Len(t, repo.Devices, 1)
+	assert.Equal(t, "2", repo.Devices[0].ID)
+}
+
+func TestBatchDevices_Errors(t *testing.T) {
+	router := setupRouter(&device.MockRepository{})
+
+	for path, body := range map[string]string{
+		"/devices:batchGet":    `{"ids": []}`,
+		"/devices:batchDelete": `{"ids": "1"}`,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/devices:batchUpdate", bytes.NewBufferString(`{"ids": ["1"]}`))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	router = setupRouter(&device.MockRepository{Err: errors.New("internal error")})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/devices:batchGet", bytes.NewBufferString(`{"ids": ["1"]}`))
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeChecker struct {
+	pingErr         error
+	current, latest int
+	migrationErr    error
+}
+
+func (f *fakeChecker) Ping(ctx context.Context) error {
+	return f.pingErr
+}
+
+func (f *fakeChecker) MigrationStatus(ctx context.Context) (int, int, error) {
+	return f.current, f.latest, f.migrationErr
+}
+
+func setupHealthRouter(h *health) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", h.liveness)
+	router.GET("/readyz", h.readiness)
+	return router
+}
+
+func TestReadiness_Ready(t *testing.T) {
+	router := setupHealthRouter(&health{checker: &fakeChecker{current: 2, latest: 2}})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "OK", "checks": {"database": "ok", "migrations": "ok: version 2"}}`, w.Body.String())
+}
+
+func TestReadiness_DatabaseDown(t *testing.T) {
+	checker := &fakeChecker{pingErr: errors.New("connection refused"), migrationErr: errors.New("connection refused")}
+	router := setupHealthRouter(&health{checker: checker})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status": "Service Unavailable", "checks": {"database": "connection refused", "migrations": "connection refused"}}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/healthz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestReadiness_PendingMigrations(t *testing.T) {
+	router := setupHealthRouter(&health{checker: &fakeChecker{current: 1, latest: 2}})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status": "Service Unavailable", "checks": {"database": "ok", "migrations": "pending: version 1 of 2"}}`, w.Body.String())
+}
+
+func TestReadiness_Draining(t *testing.T) {
+	h := &health{checker: &fakeChecker{current: 2, latest: 2}}
+	h.draining.Store(true)
+	router := setupHealthRouter(h)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status": "Service Unavailable", "checks": {"database": "ok", "migrations": "ok: version 2", "shutdown": "draining"}}`, w.Body.String())
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/logging"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/tracing"
+	"go.uber.org/zap"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+
+	// principalKey is the gin context key under which authentication stores the caller identity.
+	principalKey = "principal"
+)
+
+// accessLogMiddleware assigns each request an id, taken from a well-formed
+// X-Request-ID header or generated, echoes it in the response, attaches a
+// logger carrying it (and the trace ids) to the request context, and emits one
+// structured line once the request completes.
+func accessLogMiddleware(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		ctx := c.Request.Context()
+		requestLogger := logger.With(append([]zap.Field{zap.String("requestId", requestID)}, tracing.LogFields(ctx)...)...)
+		c.Request = c.Request.WithContext(logging.WithLogger(ctx, requestLogger))
+
+		c.Next()
+
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("route", c.FullPath()),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", c.Writer.Size()),
+			zap.String("clientIp", c.ClientIP()),
+		}
+		if principal := c.GetString(principalKey); principal != "" {
+			fields = append(fields, zap.String("principal", principal))
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("errors", c.Errors.String()))
+		}
+
+		requestLogger.Info("request", fields...)
+	}
+}
+
+// validRequestID accepts client-supplied ids of reasonable length made of
+// printable ASCII, so they are safe to echo and log.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func setupLoggedRouter(repo device.Repository) (*gin.Engine, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(accessLogMiddleware(logger))
+
+	h := &handler{
+		logger:           logger,
+		deviceRepository: repo,
+	}
+	router.GET("/devices/:id", h.getDeviceByID)
+
+	return router, logs
+}
+
+func TestAccessLog_GeneratesRequestID(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+		},
+	}
+	router, logs := setupLoggedRouter(repo)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/devices/1", nil)
+	router.ServeHTTP(w, req)
+
+	requestID := w.Header().Get(requestIDHeader)
+	assert.NotEmpty(t, requestID)
+
+	entries := logs.FilterMessage("request").All()
+	require.Len(t, entries, 1)
+
+	fields := entries[0].ContextMap()
+	assert.Equal(t, requestID, fields["requestId"])
+	assert.Equal(t, "GET", fields["method"])
+	assert.Equal(t, "/devices/:id", fields["route"])
+	assert.Equal(t, int64(http.StatusOK), fields["status"])
+	assert.Equal(t, int64(w.Body.Len()), fields["bytes"])
+	assert.NotContains(t, fields, "principal")
+}
+
+func TestAccessLog_PropagatesRequestIDToHandlerLogs(t *testing.T) {
+	repo := &device.MockRepository{
+		Err: errors.New("internal error"),
+	}
+	router, logs := setupLoggedRouter(repo)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/devices/1", nil)
+	req.Header.Set(requestIDHeader, "req-123")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "req-123", w.Header().Get(requestIDHeader))
+
+	entries := logs.FilterField(zap.String("requestId", "req-123")).All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "error getting device by id", entries[0].Message)
+	assert.Equal(t, "request", entries[1].Message)
+}
+
+func TestAccessLog_ReplacesMalformedRequestID(t *testing.T) {
+	router, _ := setupLoggedRouter(&device.MockRepository{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/devices/1", nil)
+	req.Header.Set(requestIDHeader, "bad id\n")
+	router.ServeHTTP(w, req)
+
+	assert.NotEqual(t, "bad id\n", w.Header().Get(requestIDHeader))
+	assert.NotEmpty(t, w.Header().Get(requestIDHeader))
+}
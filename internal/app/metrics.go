@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+)
+
+// httpMetrics records per-route request counts and latencies.
+type httpMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func newHTTPMetrics(registerer prometheus.Registerer) *httpMetrics {
+	labels := []string{"method", "route", "status"}
+
+	m := &httpMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of HTTP requests handled.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency.",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+	}
+
+	registerer.MustRegister(m.requests, m.duration)
+
+	return m
+}
+
+// middleware observes every request, labelled by its route template so
+// path parameters such as device ids do not blow up cardinality.
+func (m *httpMetrics) middleware(c *gin.Context) {
+	start := time.Now()
+
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+
+	status := strconv.Itoa(c.Writer.Status())
+
+	m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+	m.duration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+}
+
+var devicesDesc = prometheus.NewDesc("devices_stored", "Number of devices stored, per brand.", []string{"brand"}, nil)
+
+const (
+	// deviceCountTimeout bounds the query behind a scrape, so a slow
+	// database fails the gauges instead of hanging /metrics.
+	deviceCountTimeout = 5 * time.Second
+	// deviceCountMaxAge is how long counts are reused, about one scrape
+	// interval, so frequent or concurrent scrapes share one query.
+	deviceCountMaxAge = 15 * time.Second
+)
+
+// deviceCollector exports inventory gauges, queried from the repository at
+// most once per maxAge.
+type deviceCollector struct {
+	deviceRepository device.Repository
+	timeout          time.Duration
+	maxAge           time.Duration
+
+	// mu serialises scrapes, so at most one query holds a connection.
+	mu        sync.Mutex
+	counts    map[string]int
+	fetchedAt time.Time
+}
+
+func newDeviceCollector(deviceRepository device.Repository) *deviceCollector {
+	return &deviceCollector{
+		deviceRepository: deviceRepository,
+		timeout:          deviceCountTimeout,
+		maxAge:           deviceCountMaxAge,
+	}
+}
+
+func (d *deviceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- devicesDesc
+}
+
+func (d *deviceCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := d.load()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(devicesDesc, err)
+		return
+	}
+
+	for brand, count := range counts {
+		ch <- prometheus.MustNewConstMetric(devicesDesc, prometheus.GaugeValue, float64(count), brand)
+	}
+}
+
+// load returns the counts of the last query if it is recent enough, and
+// queries them again otherwise. Failures are not cached.
+func (d *deviceCollector) load() (map[string]int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.counts != nil && time.Since(d.fetchedAt) < d.maxAge {
+		return d.counts, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	counts, err := d.deviceRepository.CountByBrand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.counts, d.fetchedAt = counts, time.Now()
+	return counts, nil
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+)
+
+func TestHTTPMetrics_LabelsByRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	metrics := newHTTPMetrics(prometheus.NewRegistry())
+	router := gin.New()
+	router.Use(metrics.middleware)
+	router.GET("/instrumented/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, path := range []string{"/instrumented/1", "/instrumented/2", "/missing"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.requests.WithLabelValues("GET", "/instrumented/:id", "204")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.requests.WithLabelValues("GET", "unmatched", "404")))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.duration))
+}
+
+func TestDeviceCollector(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+			{ID: "2", Name: "Device2", Brand: "BrandA"},
+			{ID: "3", Name: "Device3", Brand: "BrandB"},
+		},
+	}
+
+	expected := `
+# HELP devices_stored Number of devices stored, per brand.
+# TYPE devices_stored gauge
+devices_stored{brand="BrandA"} 2
+devices_stored{brand="BrandB"} 1
+`
+	err := testutil.CollectAndCompare(newDeviceCollector(repo), strings.NewReader(expected))
+	require.NoError(t, err)
+}
+
+func TestDeviceCollector_Error(t *testing.T) {
+	repo := &device.MockRepository{Err: errors.New("internal error")}
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(newDeviceCollector(repo))
+
+	_, err := registry.Gather()
+	assert.ErrorContains(t, err, "internal error")
+}
+
+func TestDeviceCollector_ReusesRecentCounts(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+		},
+	}
+	collector := newDeviceCollector(repo)
+
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP devices_stored Number of devices stored, per brand.
+# TYPE devices_stored gauge
+devices_stored{brand="BrandA"} 1
+`)))
+
+	repo.Devices = append(repo.Devices, device.Device{ID: "2", Name: "Device2", Brand: "BrandA"})
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector), "counts are reused within maxAge")
+
+	collector.fetchedAt = collector.fetchedAt.Add(-deviceCountMaxAge)
+	assert.Equal(t, 2.0, testutil.ToFloat64(collector))
+}
+
+// blockingRepository never answers before its context is done.
+type blockingRepository struct {
+	device.MockRepository
+}
+
+func (*blockingRepository) CountByBrand(ctx context.Context, brands ...string) (map[string]int, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestDeviceCollector_Timeout(t *testing.T) {
+	collector := newDeviceCollector(&blockingRepository{})
+	collector.timeout = 10 * time.Millisecond
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+
+	_, err := registry.Gather()
+	assert.ErrorContains(t, err, context.DeadlineExceeded.Error())
+}
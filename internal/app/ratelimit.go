@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/logging"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit"
+	"go.uber.org/zap"
+)
+
+// rateLimitPolicy is the default limit plus per-route overrides, keyed by
+// "METHOD /route" with the route template as registered.
+type rateLimitPolicy struct {
+	limit  ratelimit.Limit
+	routes map[string]int
+}
+
+// rateLimiter enforces a per-client policy that can be replaced while
+// requests are being served.
+type rateLimiter struct {
+	logger  *zap.Logger
+	limiter *ratelimit.Limiter
+	policy  atomic.Pointer[rateLimitPolicy]
+}
+
+func newRateLimiter(logger *zap.Logger, store ratelimit.Store, cfg config.RateLimit) *rateLimiter {
+	r := &rateLimiter{logger: logger, limiter: ratelimit.New(store)}
+	r.setPolicy(cfg)
+	return r
+}
+
+// setPolicy swaps the limits of a validated configuration.
+func (r *rateLimiter) setPolicy(cfg config.RateLimit) {
+	routes, _ := cfg.RouteLimits()
+	r.policy.Store(&rateLimitPolicy{
+		limit:  ratelimit.Limit{Requests: cfg.Requests, Period: cfg.Period},
+		routes: routes,
+	})
+}
+
+// middleware counts the request against the caller's quota, identified by
+// the authenticated principal or else the client IP, and rejects it with 429
+// once the quota is spent. Routes with their own limit have their own quota.
+// Responses carry the RateLimit-* headers; if the store fails the request is
+// let through.
+func (r *rateLimiter) middleware(c *gin.Context) {
+	client := clientKey(c.GetString(principalKey), c.ClientIP())
+	limit, result, checked := r.check(c.Request.Context(), client, c.Request.Method+" "+unversionedRoute(c))
+	if !checked {
+		c.Next()
+		return
+	}
+
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, seconds(limit.Period)))
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+
+	if !result.Allowed {
+		c.Header("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+		abortWith(c, http.StatusTooManyRequests, gin.H{
+			"error": "rate limit exceeded",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// check counts a call to route against the quota of client, using the
+// route's own limit and quota if it has one. It reports false, letting the
+// call through, when rate limiting is off or the store fails.
+func (r *rateLimiter) check(ctx context.Context, client, route string) (ratelimit.Limit, ratelimit.Result, bool) {
+	policy := r.policy.Load()
+
+	key := client
+	limit := policy.limit
+	if requests, found := policy.routes[route]; found {
+		limit.Requests = requests
+		key += " " + route
+	}
+	if limit.Requests == 0 {
+		return limit, ratelimit.Result{}, false
+	}
+
+	result, err := r.limiter.Allow(ctx, key, limit)
+	if err != nil {
+		logging.FromContext(ctx, r.logger).Error("rate limit check failed, allowing request", zap.Error(err))
+		return limit, ratelimit.Result{}, false
+	}
+	return limit, result, true
+}
+
+// clientKey identifies a caller by its authenticated principal, or else by
+// its IP address, so REST and gRPC calls share one quota.
+func clientKey(principal, ip string) string {
+	if principal != "" {
+		return "principal:" + principal
+	}
+	return "ip:" + ip
+}
+
+// seconds rounds d up to whole seconds, as the rate limit headers expect.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
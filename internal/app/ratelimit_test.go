@@ -0,0 +1,176 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit"
+	"go.uber.org/zap"
+)
+
+func setupRateLimitedRouter(store ratelimit.Store, cfg config.RateLimit) (*gin.Engine, *rateLimiter) {
+	limits := newRateLimiter(zap.NewNop(), store, cfg)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if principal := c.GetHeader(apiKeyHeader); principal != "" {
+			c.Set(principalKey, principal)
+		}
+	}, limits.middleware)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/devices", ok)
+	router.GET("/devices/:id", ok)
+
+	return router, limits
+}
+
+func rateLimitedRequest(router *gin.Engine, path, principal string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", path, nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	if principal != "" {
+		req.Header.Set(apiKeyHeader, principal)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_RejectsOverQuota(t *testing.T) {
+	router, _ := setupRateLimitedRouter(ratelimit.NewMemoryStore(), config.RateLimit{Requests: 2, Period: time.Minute})
+
+	w := rateLimitedRequest(router, "/devices", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", w.Header().Get("RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(router, "/devices/1", "").Code)
+
+	w = rateLimitedRequest(router, "/devices", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"rate limit exceeded"}`, w.Body.String())
+}
+
+func TestRateLimit_KeysByPrincipalThenIP(t *testing.T) {
+	router, _ := setupRateLimitedRouter(ratelimit.NewMemoryStore(), config.RateLimit{Requests: 1, Period: time.Minute})
+
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(router, "/devices", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(router, "/devices", "").Code)
+
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(router, "/devices", "ci").Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(router, "/devices", "ci").Code)
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(router, "/devices", "ops").Code)
+}
+
+func TestRateLimit_RouteOverrides(t *testing.T) {
+	router, _ := setupRateLimitedRouter(ratelimit.NewMemoryStore(), config.RateLimit{
+		Requests: 1,
+		Period:   time.Minute,
+		Routes:   []string{"GET /devices=3", "GET /devices/:id=0"},
+	})
+
+	for i := 0; i < 3; i++ {
+		w := rateLimitedRequest(router, "/devices", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(router, "/devices", "").Code)
+
+	for i := 0; i < 5; i++ {
+		w := rateLimitedRequest(router, "/devices/1", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimit_SetPolicy(t *testing.T) {
+	router, limits := setupRateLimitedRouter(ratelimit.NewMemoryStore(), config.RateLimit{Period: time.Minute})
+
+	w := rateLimitedRequest(router, "/devices", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+
+	limits.setPolicy(config.RateLimit{Requests: 1, Period: time.Minute})
+
+	assert.Equal(t, http.StatusOK, rateLimitedRequest(router, "/devices", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, rateLimitedRequest(router, "/devices", "").Code)
+}
+
+type failingStore struct{}
+
+func (failingStore) Reserve(context.Context, string, time.Time, time.Duration, time.Duration) (time.Time, bool, error) {
+	return time.Time{}, false, errors.New("connection refused")
+}
+
+func TestRateLimit_AllowsWhenStoreFails(t *testing.T) {
+	router, _ := setupRateLimitedRouter(failingStore{}, config.RateLimit{Requests: 1, Period: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, rateLimitedRequest(router, "/devices", "").Code)
+	}
+}
+
+func TestRateLimit_ProductionRouter(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateLimit.Requests = 1
+	cfg.RateLimit.Period = time.Hour
+	cfg.Auth.APIKeys = []string{"ci:k1"}
+	router := NewRouter(cfg, Options{Logger: zap.NewNop(), Devices: &device.MockRepository{Devices: []device.Device{{ID: "1"}}}, Registry: prometheus.NewRegistry()})
+
+	request := func(key, forwardedFor string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/v1/devices", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if key != "" {
+			req.Header.Set(apiKeyHeader, key)
+		}
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Failed attempts count against the client IP, and forwarding headers
+	// from an untrusted peer do not change it.
+	assert.Equal(t, http.StatusUnauthorized, request("guess", "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, request("guess", "198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, request("", "198.51.100.3"))
+
+	assert.Equal(t, http.StatusOK, request("k1", ""))
+	assert.Equal(t, http.StatusTooManyRequests, request("k1", ""))
+}
+
+func TestRateLimit_TrustedProxies(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateLimit.Requests = 1
+	cfg.RateLimit.Period = time.Hour
+	cfg.HTTP.TrustedProxies = []string{"192.0.2.0/24"}
+	router := NewRouter(cfg, Options{Logger: zap.NewNop(), Devices: &device.MockRepository{Devices: []device.Device{{ID: "1"}}}, Registry: prometheus.NewRegistry()})
+
+	request := func(forwardedFor string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/v1/devices", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("198.51.100.1"))
+	assert.Equal(t, http.StatusOK, request("198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, request("198.51.100.2"))
+}
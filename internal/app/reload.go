@@ -0,0 +1,45 @@
+package app
+
+import (
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// reloadable holds the settings swapped in place on SIGHUP, without
+// restarting the HTTP server or the database pool. Everything else in the
+// configuration only takes effect on restart.
+type reloadable struct {
+	load     func() (config.Config, error)
+	logger   *zap.Logger
+	logLevel zap.AtomicLevel
+	auth     *authenticator
+	limits   *rateLimiter
+}
+
+// reload re-reads the configuration and applies it; an invalid configuration
+// is logged and the running settings are kept.
+func (r *reloadable) reload() {
+	cfg, err := r.load()
+	if err != nil {
+		r.logger.With(zap.Error(err)).Error("configuration reload failed, keeping current settings")
+		return
+	}
+
+	r.apply(cfg)
+	r.logger.With(
+		zap.String("logLevel", r.logLevel.String()),
+		zap.Int("apiKeys", len(cfg.Auth.APIKeys)),
+		zap.Int("rateLimitRequests", cfg.RateLimit.Requests),
+		zap.Duration("rateLimitPeriod", cfg.RateLimit.Period),
+	).Info("configuration reloaded")
+}
+
+// apply swaps the reloadable settings of a validated configuration.
+func (r *reloadable) apply(cfg config.Config) {
+	if level, err := zapcore.ParseLevel(cfg.Log.Level); err == nil {
+		r.logLevel.SetLevel(level)
+	}
+	r.auth.setPrincipals(cfg.Auth.Principals())
+	r.limits.setPolicy(cfg.RateLimit)
+}
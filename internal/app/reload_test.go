@@ -0,0 +1,57 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestReload_SwapsLogLevelAndAPIKeys(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	cfg := config.Default()
+	cfg.Log.Level = "debug"
+	cfg.Auth.APIKeys = []string{"ci:k1"}
+	cfg.RateLimit.Requests = 100
+	cfg.RateLimit.Routes = []string{"GET /devices=10"}
+
+	r := &reloadable{
+		load:     func() (config.Config, error) { return cfg, nil },
+		logger:   zap.New(core),
+		logLevel: zap.NewAtomicLevelAt(zapcore.InfoLevel),
+		auth:     newAuthenticator(nil),
+		limits:   newRateLimiter(zap.NewNop(), ratelimit.NewMemoryStore(), config.Default().RateLimit),
+	}
+	r.reload()
+
+	assert.Equal(t, zapcore.DebugLevel, r.logLevel.Level())
+	assert.Equal(t, map[string]string{"k1": "ci"}, *r.auth.principals.Load())
+	assert.Equal(t, &rateLimitPolicy{
+		limit:  ratelimit.Limit{Requests: 100, Period: time.Minute},
+		routes: map[string]int{"GET /devices": 10},
+	}, r.limits.policy.Load())
+	assert.Equal(t, 1, logs.FilterMessage("configuration reloaded").Len())
+}
+
+func TestReload_KeepsSettingsOnError(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	r := &reloadable{
+		load:     func() (config.Config, error) { return config.Config{}, errors.New("log.level: unknown level \"loud\"") },
+		logger:   zap.New(core),
+		logLevel: zap.NewAtomicLevelAt(zapcore.WarnLevel),
+		auth:     newAuthenticator(map[string]string{"k1": "ci"}),
+		limits:   newRateLimiter(zap.NewNop(), ratelimit.NewMemoryStore(), config.Default().RateLimit),
+	}
+	r.reload()
+
+	assert.Equal(t, zapcore.WarnLevel, r.logLevel.Level())
+	assert.Equal(t, map[string]string{"k1": "ci"}, *r.auth.principals.Load())
+	assert.Equal(t, 1, logs.FilterMessage("configuration reload failed, keeping current settings").Len())
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/watch"
+	"go.uber.org/zap"
+)
+
+// server is the HTTP router together with the state Run adjusts while it is
+// serving.
+type server struct {
+	router *gin.Engine
+	events *watch.Repository
+	health *health
+	auth   *authenticator
+	limits *rateLimiter
+	cache  *cacheControl
+}
+
+// NewRouter returns the handler serving the HTTP API exactly as Run does,
+// middleware included, for callers that run it themselves, such as tests.
+// Options.Load and Options.Lifecycle are not used.
+func NewRouter(cfg config.Config, opts Options) http.Handler {
+	return newServer(cfg, opts).router
+}
+
+func newServer(cfg config.Config, opts Options) *server {
+	logger := opts.Logger
+
+	var (
+		registerer prometheus.Registerer = prometheus.DefaultRegisterer
+		gatherer   prometheus.Gatherer   = prometheus.DefaultGatherer
+	)
+	if opts.Registry != nil {
+		registerer, gatherer = opts.Registry, opts.Registry
+	}
+
+	rateLimits := opts.RateLimits
+	if rateLimits == nil {
+		rateLimits = ratelimit.NewMemoryStore()
+	}
+
+	s := &server{
+		// Writes from every API go through events, so gRPC watchers see them all.
+		events: watch.New(opts.Devices),
+		health: &health{checker: opts.Health},
+		auth:   newAuthenticator(cfg.Auth.Principals()),
+		limits: newRateLimiter(logger, rateLimits, cfg.RateLimit),
+		cache:  newCacheControl(cfg.HTTP),
+	}
+
+	handler := &handler{
+		logger:           logger,
+		deviceRepository: s.events,
+	}
+	graphql := newGraphQLHandler(logger, s.events)
+	// Validate has checked the date.
+	sunset, _ := cfg.HTTP.LegacySunsetTime()
+	legacy := legacyRoutes{sunset: sunset}
+
+	metrics := newHTTPMetrics(registerer)
+	registerer.MustRegister(newDeviceCollector(opts.Devices))
+
+	router := gin.New()
+	// Routes have one canonical path, without a trailing slash: requests
+	// with one are redirected (301 for GET, 307 otherwise, keeping the
+	// method and body), other spellings are not guessed at, and a path served
+	// only for other methods answers 405 listing them in Allow.
+	router.RedirectTrailingSlash = true
+	router.RedirectFixedPath = false
+	router.HandleMethodNotAllowed = true
+	// Validate has checked the addresses; without any the client IP is the
+	// peer address and forwarding headers are ignored.
+	_ = router.SetTrustedProxies(cfg.HTTP.TrustedProxies)
+	router.NoRoute(notFound)
+	router.NoMethod(methodNotAllowed)
+	router.Use(tracingMiddleware, accessLogMiddleware(logger), metrics.middleware, compressMiddleware, gin.Recovery(), maxBodyMiddleware(int64(cfg.HTTP.MaxBodyBytes)), s.cache.middleware)
+
+	router.GET("/", handler.healthCheck)
+	router.GET("/healthz", s.health.liveness)
+	router.GET("/readyz", s.health.readiness)
+	router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
+		ErrorLog:      zap.NewStdLog(logger),
+		ErrorHandling: promhttp.ContinueOnError,
+	})))
+	router.GET("/docs/*any", docsHandler())
+
+	for _, version := range apiVersions {
+		s.deviceRoutes(router.Group(versionPrefix(version), versionMiddleware(version)), handler)
+	}
+	s.deviceRoutes(router.Group("", legacy.middleware), handler)
+
+	router.POST("/graphql", append(s.protected(), graphql.serve)...)
+
+	s.router = router
+	return s
+}
+
+// deviceRoutes registers the REST API on group, once per version and once
+// at the root for the deprecated unversioned routes.
+func (s *server) deviceRoutes(group *gin.RouterGroup, handler *handler) {
+	devices := group.Group("devices", s.protected()...)
+
+	devices.GET("", handler.listAllDevices)
+	devices.GET("/:id", handler.getDeviceByID)
+	devices.GET("/search", handler.searchDevices)
+	devices.GET("/stats", handler.deviceStats)
+
+	devices.POST("", handler.addDevice)
+
+	devices.PATCH("/:id", handler.updateDevice)
+
+	devices.DELETE("/:id", handler.deleteDevice)
+
+	// Custom methods in the "/devices:action" style; gin has no escape for
+	// the colon, so it routes any path continuing /devices as a parameter,
+	// and knownAction turns away the ones that are not custom methods.
+	group.POST("/devices:action", append(append([]gin.HandlerFunc{knownAction}, s.protected()...), handler.batchAction)...)
+}
+
+// knownAction answers 404 for paths the custom method route catches without
+// naming a custom method, such as /devicesfoo, before anything else runs.
+func knownAction(c *gin.Context) {
+	if _, found := batchActions[c.Param("action")]; !found {
+		notFound(c)
+		c.Abort()
+	}
+}
+
+// unknownAction reports whether path continues /devices, under any version
+// prefix, with something other than a custom method.
+func unknownAction(path string) bool {
+	if version, found := pathVersion(path); found {
+		path = strings.TrimPrefix(path, versionPrefix(version))
+	}
+	action, found := strings.CutPrefix(path, "/devices")
+	if !found || action == "" || action[0] == '/' {
+		return false
+	}
+	_, known := batchActions[action]
+	return !known
+}
+
+// protected returns the middleware guarding the APIs: callers are identified
+// and rate limited before being required to authenticate, so failed attempts
+// use up the quota of their IP.
+func (s *server) protected() []gin.HandlerFunc {
+	return []gin.HandlerFunc{s.auth.identify, s.limits.middleware, s.auth.require}
+}
+
+// notFound answers requests for paths no route serves, in the envelope of
+// the API version their prefix names.
+func notFound(c *gin.Context) {
+	if version, found := pathVersion(c.Request.URL.Path); found {
+		c.Set(apiVersionKey, version)
+	}
+	respond(c, http.StatusNotFound, gin.H{
+		"status": http.StatusText(http.StatusNotFound),
+	})
+}
+
+// methodNotAllowed answers requests whose path is served only for other
+// methods; gin has already listed them in the Allow header. Paths matching
+// the custom method route only by its parameter are not served at all.
+func methodNotAllowed(c *gin.Context) {
+	if unknownAction(c.Request.URL.Path) {
+		c.Writer.Header().Del("Allow")
+		notFound(c)
+		return
+	}
+	if version, found := pathVersion(c.Request.URL.Path); found {
+		c.Set(apiVersionKey, version)
+	}
+	respond(c, http.StatusMethodNotAllowed, gin.H{
+		"status": http.StatusText(http.StatusMethodNotAllowed),
+	})
+}
+
+// docsHandler serves the Swagger UI of every API version under /docs/vN/,
+// sending other paths to the latest version's.
+func docsHandler() gin.HandlerFunc {
+	latest := versionPrefix(apiVersions[len(apiVersions)-1])
+	handlers := make(map[string]gin.HandlerFunc, len(apiVersions))
+	for _, version := range apiVersions {
+		prefix := versionPrefix(version)
+		handlers[prefix] = ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.InstanceName(prefix[1:]))
+	}
+
+	return func(c *gin.Context) {
+		prefix, _, _ := strings.Cut(strings.TrimPrefix(c.Param("any"), "/"), "/")
+		if handler, found := handlers["/"+prefix]; found {
+			handler(c)
+			return
+		}
+		c.Redirect(http.StatusFound, "/docs"+latest+"/index.html")
+	}
+}
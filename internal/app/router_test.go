@@ -0,0 +1,105 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"go.uber.org/zap"
+)
+
+func TestRouter_RouteTable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := newServer(config.Default(), Options{
+		Logger:   zap.NewNop(),
+		Devices:  &device.MockRepository{},
+		Registry: prometheus.NewRegistry(),
+	})
+
+	want := []string{
+		"GET /",
+		"GET /healthz",
+		"GET /readyz",
+		"GET /metrics",
+		"GET /docs/*any",
+		"POST /graphql",
+	}
+	for _, prefix := range []string{"", "/v1", "/v2"} {
+		want = append(want,
+			"GET "+prefix+"/devices",
+			"GET "+prefix+"/devices/:id",
+			"GET "+prefix+"/devices/search",
+			"GET "+prefix+"/devices/stats",
+			"POST "+prefix+"/devices",
+			"PATCH "+prefix+"/devices/:id",
+			"DELETE "+prefix+"/devices/:id",
+			"POST "+prefix+"/devices:action",
+		)
+	}
+
+	var got []string
+	for _, route := range s.router.Routes() {
+		got = append(got, route.Method+" "+route.Path)
+	}
+	assert.ElementsMatch(t, want, got)
+}
+
+func TestRouter_TrailingSlashAndMethods(t *testing.T) {
+	router := setupRouter(&device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+		},
+	})
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(`{"name": "Device2", "brand": "BrandB"}`)))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve("GET", "/v1/devices").Code)
+
+	w := serve("GET", "/v1/devices/?pageSize=1")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/v1/devices?pageSize=1", w.Header().Get("Location"))
+
+	w = serve("POST", "/devices/")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code, "keeps the method and body")
+	assert.Equal(t, "/devices", w.Header().Get("Location"))
+
+	w = serve("GET", "/v1/devices/1/")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/v1/devices/1", w.Header().Get("Location"))
+
+	w = serve("PUT", "/v1/devices/1")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.ElementsMatch(t, []string{"GET", "PATCH", "DELETE"}, strings.Split(w.Header().Get("Allow"), ", "))
+	assert.JSONEq(t, `{"status": "Method Not Allowed"}`, w.Body.String())
+
+	w = serve("GET", "/v2/devices:batchGet")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "POST", w.Header().Get("Allow"))
+	assert.JSONEq(t, `{"error": {"code": 405, "message": "Method Not Allowed"}}`, w.Body.String())
+
+	// The custom method route serves only its actions.
+	for _, method := range []string{"GET", "POST"} {
+		for _, path := range []string{"/devicesfoo", "/v2/devicesX", "/v1/devices:batchUpdate"} {
+			w = serve(method, path)
+			assert.Equal(t, http.StatusNotFound, w.Code, method+" "+path)
+			assert.Empty(t, w.Header().Get("Allow"), method+" "+path)
+		}
+	}
+
+	w = serve("GET", "/v2/gadgets")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error": {"code": 404, "message": "Not Found"}}`, w.Body.String())
+
+	w = serve("GET", "/Devices")
+	assert.Equal(t, http.StatusNotFound, w.Code, "paths are not case-corrected")
+	assert.JSONEq(t, `{"status": "Not Found"}`, w.Body.String())
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"go.uber.org/zap"
+)
+
+// certificateCheckInterval bounds how often handshakes stat the certificate files.
+const certificateCheckInterval = 5 * time.Second
+
+// certificateReloader serves a certificate pair from disk and reloads it when
+// either file changes, so renewed certificates are picked up without a
+// restart. A pair that fails to load is logged and the previous one kept.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertificateReloader(certFile, keyFile string, logger *zap.Logger) (*certificateReloader, error) {
+	r := &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   logger,
+		now:      time.Now,
+	}
+
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// getCertificate implements tls.Config.GetCertificate.
+func (r *certificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := r.now(); now.Sub(r.checked) >= certificateCheckInterval {
+		r.checked = now
+		if modTime, err := r.latestModTime(); err != nil {
+			r.logger.With(zap.Error(err)).Error("unable to check TLS certificate, keeping current one")
+		} else if !modTime.Equal(r.modTime) {
+			if err := r.load(modTime); err != nil {
+				r.logger.With(zap.Error(err)).Error("unable to reload TLS certificate, keeping current one")
+			} else {
+				r.logger.With(zap.String("certFile", r.certFile)).Info("TLS certificate reloaded")
+			}
+		}
+	}
+
+	return r.cert, nil
+}
+
+func (r *certificateReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+// latestModTime returns the newer modification time of the two files.
+func (r *certificateReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// newTLSConfig builds the server TLS configuration, verifying client
+// certificates against the client CA when mutual TLS is enabled.
+func newTLSConfig(cfg config.TLS, logger *zap.Logger) (*tls.Config, error) {
+	certificates, err := newCertificateReloader(cfg.CertFile, cfg.KeyFile, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certificates.getCertificate,
+	}
+
+	switch cfg.ClientAuth {
+	case "optional":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading client CA: %w", err)
+	}
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, errors.New("client CA file contains no PEM certificates")
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"go.uber.org/zap"
+)
+
+// testCA issues certificates for TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for name, usable by servers and clients.
+func (ca *testCA) issue(t *testing.T, name string, serial int64) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeTestFile(t *testing.T, dir, name string, content []byte, modTime time.Time) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	return path
+}
+
+func TestCertificateReloader_ReloadsChangedFiles(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+
+	certPEM, keyPEM := ca.issue(t, "localhost", 2)
+	certFile := writeTestFile(t, dir, "tls.crt", certPEM, start)
+	keyFile := writeTestFile(t, dir, "tls.key", keyPEM, start)
+
+	reloader, err := newCertificateReloader(certFile, keyFile, zap.NewNop())
+	require.NoError(t, err)
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	serial := func() int64 {
+		cert, err := reloader.getCertificate(nil)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		return leaf.SerialNumber.Int64()
+	}
+	assert.Equal(t, int64(2), serial())
+
+	certPEM, keyPEM = ca.issue(t, "localhost", 3)
+	writeTestFile(t, dir, "tls.crt", certPEM, start.Add(time.Second))
+	writeTestFile(t, dir, "tls.key", keyPEM, start.Add(time.Second))
+
+	assert.Equal(t, int64(2), serial(), "files are not checked again within the interval")
+
+	now = now.Add(certificateCheckInterval)
+	assert.Equal(t, int64(3), serial())
+
+	writeTestFile(t, dir, "tls.crt", []byte("garbage"), start.Add(2*time.Second))
+	now = now.Add(certificateCheckInterval)
+	assert.Equal(t, int64(3), serial(), "a broken pair keeps the current certificate")
+}
+
+func TestTLS_MutualAuthentication(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+
+	certPEM, keyPEM := ca.issue(t, "localhost", 2)
+	tlsConfig, err := newTLSConfig(config.TLS{
+		CertFile:     writeTestFile(t, dir, "tls.crt", certPEM, time.Now()),
+		KeyFile:      writeTestFile(t, dir, "tls.key", keyPEM, time.Now()),
+		ClientCAFile: writeTestFile(t, dir, "ca.crt", ca.pem, time.Now()),
+		ClientAuth:   "require",
+	}, zap.NewNop())
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	auth := newAuthenticator(map[string]string{"k1": "ci"})
+	router.GET("/devices", auth.identify, auth.require, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(principalKey))
+	})
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.NoError(t, err)
+	srv := &http.Server{Handler: router, ErrorLog: zap.NewStdLog(zap.NewNop())}
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Close() })
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	url := "https://" + listener.Addr().String() + "/devices"
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err = anonymous.Get(url)
+	assert.Error(t, err, "handshakes without a client certificate fail")
+
+	clientCertPEM, clientKeyPEM := ca.issue(t, "inventory-sync", 4)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	require.NoError(t, err)
+	authenticated := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{clientCert},
+	}}}
+
+	resp, err := authenticated.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "cert:inventory-sync", string(body))
+}
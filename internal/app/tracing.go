@@ -0,0 +1,50 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingMiddleware starts a server span per request, continuing the trace
+// from an incoming W3C traceparent header, and stores it in the request context
+// so repository queries become its children.
+func tracingMiddleware(c *gin.Context) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+	route := c.FullPath()
+	name := c.Request.Method + " " + route
+	if route == "" {
+		name = c.Request.Method
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(c.Request.URL.Path),
+			semconv.ClientAddress(c.ClientIP()),
+		),
+	)
+	defer span.End()
+
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	status := c.Writer.Status()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	for _, err := range c.Errors {
+		span.RecordError(err.Err)
+	}
+}
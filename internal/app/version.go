@@ -0,0 +1,170 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @title Devices Service
+// @version 2.0
+// @description Devices Service for technical challenge, version 2.
+// @description Every response body is an envelope: results under "data" (with "nextPageToken" beside them on paginated listings), failures as {"error": {"code": <HTTP status>, "message": "..."}}.
+// @contact.name Victor Springer
+// @license.name MIT License
+// @host localhost:8080
+// @BasePath /v2
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+const (
+	// apiVersionHeader selects the API version on the unversioned routes and
+	// reports the version that served every versioned response.
+	apiVersionHeader = "API-Version"
+
+	// apiVersionKey is the gin context key under which the API version serving the request is stored.
+	apiVersionKey = "apiVersion"
+)
+
+// apiVersions lists the versions of the REST API, each served under /vN.
+// Version 2 wraps every body in the same envelope; see envelope.
+var apiVersions = []int{1, 2}
+
+// legacyDeprecation is when the unversioned routes, served as version 1,
+// were deprecated in favour of /v1.
+var legacyDeprecation = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+func versionPrefix(version int) string {
+	return "/v" + strconv.Itoa(version)
+}
+
+// parseAPIVersion reads an API-Version header, "2" or "v2".
+func parseAPIVersion(raw string) (int, error) {
+	version, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(raw), "v"))
+	if err == nil {
+		for _, v := range apiVersions {
+			if v == version {
+				return version, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unsupported API version %q, expected one of %v", raw, apiVersions)
+}
+
+// versionMiddleware serves a route group as the given API version.
+func versionMiddleware(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Header(apiVersionHeader, strconv.Itoa(version))
+		c.Next()
+	}
+}
+
+// legacyRoutes serves the unversioned routes: as the version an API-Version
+// header selects or else, deprecated, as version 1.
+type legacyRoutes struct {
+	// sunset is when the unversioned routes may go away; zero if unplanned.
+	sunset time.Time
+}
+
+func (l legacyRoutes) middleware(c *gin.Context) {
+	c.Writer.Header().Add("Vary", apiVersionHeader)
+
+	if raw := c.GetHeader(apiVersionHeader); raw != "" {
+		version, err := parseAPIVersion(raw)
+		if err != nil {
+			abortWith(c, http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		versionMiddleware(version)(c)
+		return
+	}
+
+	c.Header("Deprecation", "@"+strconv.FormatInt(legacyDeprecation.Unix(), 10))
+	if !l.sunset.IsZero() {
+		c.Header("Sunset", l.sunset.UTC().Format(http.TimeFormat))
+	}
+	c.Header("Link", "<"+versionPrefix(1)+c.Request.URL.Path+`>; rel="successor-version"`)
+	c.Set(apiVersionKey, 1)
+	c.Next()
+}
+
+// apiVersion returns the API version serving the request, 1 outside the REST API.
+func apiVersion(c *gin.Context) int {
+	if version, ok := c.Get(apiVersionKey); ok {
+		return version.(int)
+	}
+	return 1
+}
+
+// unversionedRoute returns the route of the request without its /vN prefix
+// or trailing slash, as per-route settings name it, so they apply to every
+// version alike.
+func unversionedRoute(c *gin.Context) string {
+	route := c.FullPath()
+	if version, found := pathVersion(route); found {
+		route = strings.TrimPrefix(route, versionPrefix(version))
+	}
+	if len(route) > 1 {
+		route = strings.TrimSuffix(route, "/")
+	}
+	return route
+}
+
+// pathVersion returns the API version whose prefix path starts with.
+func pathVersion(path string) (int, bool) {
+	for _, version := range apiVersions {
+		if rest, found := strings.CutPrefix(path, versionPrefix(version)); found && (rest == "" || rest[0] == '/') {
+			return version, true
+		}
+	}
+	return 0, false
+}
+
+// envelope reshapes a version 1 response body for version 2. Failures become
+// {"error": {"code", "message"}}. Otherwise the "status" echo is dropped, a
+// nextPageToken stays beside the data, and the data is the single remaining
+// value or, when several remain, the object holding them.
+func envelope(status int, obj any) any {
+	body, ok := obj.(gin.H)
+	if !ok {
+		return gin.H{"data": obj}
+	}
+
+	if status >= http.StatusBadRequest {
+		message, ok := body["error"]
+		if !ok {
+			message = http.StatusText(status)
+		}
+		return gin.H{"error": gin.H{"code": status, "message": message}}
+	}
+
+	data := gin.H{}
+	wrapped := gin.H{"data": nil}
+	for key, value := range body {
+		switch key {
+		case "status":
+		case "nextPageToken":
+			wrapped[key] = value
+		default:
+			data[key] = value
+		}
+	}
+	switch len(data) {
+	case 0:
+	case 1:
+		for _, value := range data {
+			wrapped["data"] = value
+		}
+	default:
+		wrapped["data"] = data
+	}
+	return wrapped
+}
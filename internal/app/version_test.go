@@ -0,0 +1,135 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/config"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"go.uber.org/zap"
+)
+
+func TestVersionedRoutes(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+		},
+	}
+	router := setupRouter(repo)
+	deviceJSON, _ := json.Marshal(repo.Devices[0])
+
+	w := conditionalRequest(router, "/v1/devices/1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("API-Version"))
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.JSONEq(t, `{"device": `+string(deviceJSON)+`}`, w.Body.String())
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"), "per-route settings apply to every version")
+
+	w = conditionalRequest(router, "/v2/devices/1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("API-Version"))
+	assert.JSONEq(t, `{"data": `+string(deviceJSON)+`}`, w.Body.String())
+
+	w = conditionalRequest(router, "/v2/devices?pageSize=1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": [`+string(deviceJSON)+`]}`, w.Body.String())
+
+	w = conditionalRequest(router, "/v2/devices/2", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error": {"code": 404, "message": "Not Found"}}`, w.Body.String())
+
+	w = conditionalRequest(router, "/v2/devices?pageSize=0", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": {"code": 400, "message": "pageSize must be a positive integer"}}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v2/devices", bytes.NewBufferString(`{"name": "Device2", "brand": "BrandB"}`))
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/v2/devices/"+repo.Devices[1].ID, w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/v2/devices/"+repo.Devices[1].ID, nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": null}`, w.Body.String())
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+		},
+	}
+	router := setupRouter(repo)
+
+	w := conditionalRequest(router, "/devices/1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "@1792281600", w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Sunset"), "no sunset is announced unless configured")
+	assert.Equal(t, `</v1/devices/1>; rel="successor-version"`, w.Header().Get("Link"))
+	assert.Contains(t, w.Body.String(), `"device"`)
+
+	w = conditionalRequest(router, "/devices/1", map[string]string{"API-Version": "2"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Equal(t, "2", w.Header().Get("API-Version"))
+	assert.Contains(t, w.Body.String(), `"data"`)
+
+	w = conditionalRequest(router, "/devices/1", map[string]string{"API-Version": "v3"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "unsupported API version \"v3\", expected one of [1 2]"}`, w.Body.String())
+}
+
+func TestLegacyRoutes_Sunset(t *testing.T) {
+	cfg := config.Default()
+	cfg.HTTP.LegacySunset = "2027-04-30"
+	router := NewRouter(cfg, Options{
+		Logger:   zap.NewNop(),
+		Devices:  &device.MockRepository{},
+		Registry: prometheus.NewRegistry(),
+	})
+
+	w := conditionalRequest(router, "/devices", nil)
+	assert.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+
+	w = conditionalRequest(router, "/v1/devices", nil)
+	assert.Empty(t, w.Header().Get("Sunset"))
+}
+
+func TestDocsPerVersion(t *testing.T) {
+	router := setupRouter(&device.MockRepository{})
+
+	for _, path := range []string{"/docs/v1/doc.json", "/docs/v2/doc.json"} {
+		w := conditionalRequest(router, path, nil)
+		require.Equal(t, http.StatusOK, w.Code, path)
+
+		var doc struct {
+			BasePath string `json:"basePath"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+		assert.Equal(t, path[len("/docs"):len("/docs/v1")], doc.BasePath)
+	}
+
+	w := conditionalRequest(router, "/docs/index.html", nil)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/docs/v2/index.html", w.Header().Get("Location"))
+}
+
+func TestEnvelope(t *testing.T) {
+	assert.Equal(t, gin.H{"data": gin.H{"total": 1, "brands": 2}},
+		envelope(http.StatusOK, gin.H{"total": 1, "brands": 2}))
+	assert.Equal(t, gin.H{"data": []int{1}, "nextPageToken": "t"},
+		envelope(http.StatusOK, gin.H{"devices": []int{1}, "nextPageToken": "t"}))
+	assert.Equal(t, gin.H{"data": 1},
+		envelope(http.StatusCreated, gin.H{"status": "Created", "device": 1}))
+	assert.Equal(t, gin.H{"error": gin.H{"code": http.StatusTooManyRequests, "message": "rate limit exceeded"}},
+		envelope(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"}))
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+)
+
+// expansions lists the relations ?expand= can inline in device responses.
+var expansions = []string{"brand"}
+
+// view shapes the devices of a response as ?fields= and ?expand= ask: only
+// the listed fields, and related resources under "_expanded". Expansions are
+// added beside the fields rather than in place of them, so clients decoding
+// a device.Device keep working.
+type view struct {
+	fields      []string
+	expandBrand bool
+	// brandCounts holds the device count of the shown brands once loaded.
+	brandCounts map[string]int
+}
+
+// brandView is an expanded brand, shaped like the GraphQL Brand type.
+type brandView struct {
+	Name        string `json:"name"`
+	DeviceCount int    `json:"deviceCount"`
+}
+
+// parseView reads ?fields= and ?expand=, answering 400 when they name
+// unknown fields or relations. It reports whether both were valid.
+func parseView(c *gin.Context) (view, bool) {
+	fields, err := device.ParseFields(c.Query("fields"))
+	if err != nil {
+		respond(c, http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return view{}, false
+	}
+
+	v := view{fields: fields}
+	for _, relation := range strings.Split(c.Query("expand"), ",") {
+		relation = strings.TrimSpace(relation)
+		switch relation {
+		case "":
+		case "brand":
+			v.expandBrand = true
+		default:
+			respond(c, http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("invalid expand %q, expected %s", relation, strings.Join(expansions, ", ")),
+			})
+			return view{}, false
+		}
+	}
+	return v, true
+}
+
+// shaped reports whether the view changes devices at all.
+func (v view) shaped() bool {
+	return len(v.fields) > 0 || v.expandBrand
+}
+
+// selects returns the fields to load from the repository: those asked for,
+// plus the brand name when the brand is expanded. Empty means all of them.
+func (v view) selects() []string {
+	if len(v.fields) == 0 || !v.expandBrand || slices.Contains(v.fields, "brand") {
+		return v.fields
+	}
+	return append(slices.Clip(v.fields), "brand")
+}
+
+// load fetches what the expanded relations of devices need.
+func (v *view) load(ctx context.Context, repo device.Repository, devices ...device.Device) error {
+	if !v.expandBrand {
+		return nil
+	}
+
+	brands := make([]string, 0, len(devices))
+	for _, dvc := range devices {
+		if !slices.Contains(brands, dvc.Brand) {
+			brands = append(brands, dvc.Brand)
+		}
+	}
+	counts, err := repo.CountByBrand(ctx, brands...)
+	if err != nil {
+		return err
+	}
+	v.brandCounts = counts
+	return nil
+}
+
+// device returns dvc as the view shows it. Expanded relations are included
+// even when fields leaves them out.
+func (v view) device(dvc device.Device) any {
+	if !v.shaped() {
+		return dvc
+	}
+
+	values := device.Project(dvc, v.fields)
+	if v.expandBrand {
+		values["_expanded"] = map[string]any{
+			"brand": brandView{Name: dvc.Brand, DeviceCount: v.brandCounts[dvc.Brand]},
+		}
+	}
+	return values
+}
+
+// devices returns devices as the view shows them.
+func (v view) devices(devices []device.Device) any {
+	if !v.shaped() {
+		return devices
+	}
+
+	shown := make([]any, len(devices))
+	for i, dvc := range devices {
+		shown[i] = v.device(dvc)
+	}
+	return shown
+}
+
+// validator identifies the view in ETags, including the expanded data that
+// changes without the devices being updated.
+func (v view) validator() string {
+	key := strings.Join(v.fields, ",")
+	if v.expandBrand {
+		brands := make([]string, 0, len(v.brandCounts))
+		for brand := range v.brandCounts {
+			brands = append(brands, brand)
+		}
+		sort.Strings(brands)
+
+		key += "|brand"
+		for _, brand := range brands {
+			key += "|" + brand + "=" + strconv.Itoa(v.brandCounts[brand])
+		}
+	}
+	return key
+}
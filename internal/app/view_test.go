@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/repository"
+)
+
+func TestListAllDevices_Fields(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA", UpdateTime: time.Unix(100, 0)},
+			{ID: "2", Name: "Device2", Brand: "BrandA", UpdateTime: time.Unix(200, 0)},
+		},
+	}
+	router := setupRouter(repo)
+
+	w := conditionalRequest(router, "/devices?fields=id,name", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"devices": [{"id": "1", "name": "Device1"}, {"id": "2", "name": "Device2"}]}`, w.Body.String())
+	etag := w.Header().Get("ETag")
+
+	w = conditionalRequest(router, "/devices?fields=id&pageSize=1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"devices": [{"id": "1"}], "nextPageToken": "`+device.Cursor{ID: "1"}.Token()+`"}`, w.Body.String())
+
+	w = conditionalRequest(router, "/devices?fields=id,name", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	w = conditionalRequest(router, "/devices", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, w.Code, "other fields are another representation")
+
+	w = conditionalRequest(router, "/devices?fields=id,serial", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "`+device.ErrInvalidField.Error()+`"}`, w.Body.String())
+}
+
+func TestGetDeviceByID_Expand(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+			{ID: "2", Name: "Device2", Brand: "BrandA"},
+			{ID: "3", Name: "Device3", Brand: "BrandB"},
+		},
+	}
+	router := setupRouter(repo)
+
+	w := conditionalRequest(router, "/devices/1?fields=name&expand=brand", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"device": {"name": "Device1", "_expanded": {"brand": {"name": "BrandA", "deviceCount": 2}}}}`, w.Body.String())
+	etag := w.Header().Get("ETag")
+
+	// Counts change without device 1 being updated.
+	repo.Devices = repo.Devices[:1]
+	w = conditionalRequest(router, "/devices/1?fields=name&expand=brand", map[string]string{"If-None-Match": etag})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"device": {"name": "Device1", "_expanded": {"brand": {"name": "BrandA", "deviceCount": 1}}}}`, w.Body.String())
+
+	w = conditionalRequest(router, "/devices/1?expand=assignment", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "invalid expand \"assignment\", expected brand"}`, w.Body.String())
+}
+
+// The mock repository ignores ListOptions.Fields, so the columns loaded for
+// an expansion are checked against SQLite.
+func TestListAllDevices_FieldsAndExpandSQLite(t *testing.T) {
+	repo, err := repository.NewSQLite(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(repo.Close)
+	for _, name := range []string{"Device1", "Device2"} {
+		require.NoError(t, repo.Store(context.Background(), &device.Device{Name: name, Brand: "BrandA"}))
+	}
+	router := setupRouter(repo)
+
+	for _, path := range []string{"/devices?fields=name&expand=brand", "/devices?fields=name&expand=brand&pageSize=10"} {
+		w := conditionalRequest(router, path, nil)
+		require.Equal(t, http.StatusOK, w.Code, path)
+		assert.JSONEq(t, `{"devices": [
+			{"name": "Device1", "_expanded": {"brand": {"name": "BrandA", "deviceCount": 2}}},
+			{"name": "Device2", "_expanded": {"brand": {"name": "BrandA", "deviceCount": 2}}}
+		]}`, w.Body.String(), path)
+	}
+}
+
+// brandCountingRepository records the brands counted through it.
+type brandCountingRepository struct {
+	device.MockRepository
+	counted [][]string
+}
+
+func (r *brandCountingRepository) CountByBrand(ctx context.Context, brands ...string) (map[string]int, error) {
+	r.counted = append(r.counted, brands)
+	return r.MockRepository.CountByBrand(ctx, brands...)
+}
+
+func TestListAllDevices_ExpandKeepsTheDeviceShape(t *testing.T) {
+	repo := &brandCountingRepository{MockRepository: device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+			{ID: "2", Name: "Device2", Brand: "BrandB"},
+			{ID: "3", Name: "Device3", Brand: "BrandC"},
+		},
+	}}
+	router := setupRouter(repo)
+
+	w := conditionalRequest(router, "/v1/devices?expand=brand&pageSize=2", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Devices []device.Device `json:"devices"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), "expanded devices still decode as device.Device")
+	require.Len(t, body.Devices, 2)
+	assert.Equal(t, "BrandA", body.Devices[0].Brand)
+	assert.Contains(t, w.Body.String(), `"_expanded":{"brand":{"name":"BrandB","deviceCount":1}}`)
+	assert.Equal(t, [][]string{{"BrandA", "BrandB"}}, repo.counted, "only the brands on the page are counted")
+}
+
+func TestSearchDevices_Fields(t *testing.T) {
+	repo := &device.MockRepository{
+		Devices: []device.Device{
+			{ID: "1", Name: "Device1", Brand: "BrandA"},
+		},
+	}
+	router := setupRouter(repo)
+
+	w := conditionalRequest(router, "/devices/search?brand=BrandA&fields=brand", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"devices": [{"brand": "BrandA"}]}`, w.Body.String())
+}
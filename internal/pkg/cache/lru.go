@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lru is a size-bounded least-recently-used map whose entries expire after a TTL.
+type lru[V any] struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	items   map[string]*list.Element
+	order   *list.List
+	evicted uint64
+}
+
+type entry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+func newLRU[V any](size int, ttl time.Duration, now func() time.Time) *lru[V] {
+	return &lru[V]{
+		size:  size,
+		ttl:   ttl,
+		now:   now,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// get returns the live value for key, dropping it if it has expired.
+func (c *lru[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, found := c.items[key]
+	if !found {
+		return zero, false
+	}
+
+	e := elem.Value.(*entry[V])
+	if !c.now().Before(e.expiresAt) {
+		c.removeElement(elem)
+		return zero, false
+	}
+
+	c.order.MoveToFront(elem)
+	return e.value, true
+}
+
+// addIfGeneration stores value under key, evicting the least recently used
+// entry when full, but only while generation still holds gen. Checking and
+// storing under the lock keeps a write invalidating the entry from slipping
+// in between.
+func (c *lru[V]) addIfGeneration(key string, value V, gen uint64, generation *atomic.Uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation.Load() != gen {
+		return
+	}
+
+	expiresAt := c.now().Add(c.ttl)
+
+	if elem, found := c.items[key]; found {
+		e := elem.Value.(*entry[V])
+		e.value = value
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry[V]{key: key, value: value, expiresAt: expiresAt})
+
+	for c.order.Len() > c.size {
+		c.removeElement(c.order.Back())
+		c.evicted++
+	}
+}
+
+// remove drops key, bumping generation under the lock so no load that
+// started before can add it back.
+func (c *lru[V]) remove(key string, generation *atomic.Uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	generation.Add(1)
+	if elem, found := c.items[key]; found {
+		c.removeElement(elem)
+	}
+}
+
+// purge drops every entry, bumping generation as remove does.
+func (c *lru[V]) purge(generation *atomic.Uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	generation.Add(1)
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+func (c *lru[V]) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *lru[V]) evictions() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.evicted
+}
+
+func (c *lru[V]) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*entry[V]).key)
+}
@@ -0,0 +1,32 @@
+package cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	hitsDesc      = prometheus.NewDesc("device_cache_hits_total", "Number of device lookups served from the cache.", nil, nil)
+	missesDesc    = prometheus.NewDesc("device_cache_misses_total", "Number of device lookups that went to the backend.", nil, nil)
+	evictionsDesc = prometheus.NewDesc("device_cache_evictions_total", "Number of entries evicted to make room for new ones.", nil, nil)
+	entriesDesc   = prometheus.NewDesc("device_cache_entries", "Number of entries currently cached.", nil, nil)
+)
+
+type collector struct {
+	repo *Repository
+}
+
+// Collector returns a Prometheus collector for the cache Stats.
+func (r *Repository) Collector() prometheus.Collector {
+	return collector{repo: r}
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.repo.Stats()
+
+	ch <- prometheus.MustNewConstMetric(hitsDesc, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(missesDesc, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(evictionsDesc, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.GaugeValue, float64(stats.Entries))
+}
@@ -0,0 +1,253 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"golang.org/x/sync/singleflight"
+)
+
+// Repository is a read-through caching decorator for a device.Repository.
+//
+// FindByID and FindByBrand results (including misses) are kept in an in-process
+// LRU for the configured TTL. Concurrent misses for the same key are collapsed
+// into a single backend call. Writes go straight to the backend and invalidate
+// the affected entries, so readers on this instance never see stale data; other
+// instances sharing the backend may serve stale entries for up to the TTL.
+type Repository struct {
+	next    device.Repository
+	devices *lru[*device.Device]
+	brands  *lru[[]device.Device]
+	group   singleflight.Group
+
+	// generation is bumped on every write, under the lock of the lru whose
+	// entries it drops, so loads that raced with a write are not cached and
+	// do not share flights with later reads.
+	generation atomic.Uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// Stats holds cache effectiveness counters.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+// New wraps next with a cache holding up to size entries per lookup kind for ttl.
+func New(next device.Repository, size int, ttl time.Duration) *Repository {
+	return newWithClock(next, size, ttl, time.Now)
+}
+
+func newWithClock(next device.Repository, size int, ttl time.Duration, now func() time.Time) *Repository {
+	return &Repository{
+		next:    next,
+		devices: newLRU[*device.Device](size, ttl, now),
+		brands:  newLRU[[]device.Device](size, ttl, now),
+	}
+}
+
+// Stats returns a snapshot of the cache counters.
+func (r *Repository) Stats() Stats {
+	return Stats{
+		Hits:      r.hits.Load(),
+		Misses:    r.misses.Load(),
+		Evictions: r.devices.evictions() + r.brands.evictions(),
+		Entries:   r.devices.len() + r.brands.len(),
+	}
+}
+
+// Store adds a new device and invalidates the cached brand listings.
+func (r *Repository) Store(ctx context.Context, dvc *device.Device) error {
+	defer r.invalidate("")
+	return r.next.Store(ctx, dvc)
+}
+
+// FindByID gets a device by its ID, from the cache when possible.
+func (r *Repository) FindByID(ctx context.Context, id string) (*device.Device, error) {
+	dvc, err := load(ctx, r, r.devices, "id:"+id, func(ctx context.Context) (*device.Device, error) {
+		return r.next.FindByID(ctx, id)
+	})
+	if dvc == nil || err != nil {
+		return nil, err
+	}
+
+	copied := *dvc
+	return &copied, nil
+}
+
+// FindByIDs gets the devices with the given IDs, serving cached ones and
+// fetching the rest in a single backend call, whose results (including misses)
+// are cached like FindByID lookups.
+func (r *Repository) FindByIDs(ctx context.Context, ids []string) ([]device.Device, error) {
+	var (
+		devices []device.Device
+		missing []string
+		seen    = make(map[string]bool, len(ids))
+	)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		dvc, found := r.devices.get("id:" + id)
+		if !found {
+			missing = append(missing, id)
+			continue
+		}
+		r.hits.Add(1)
+		if dvc != nil {
+			devices = append(devices, *dvc)
+		}
+	}
+	if len(missing) == 0 {
+		device.SortDevices(devices)
+		return devices, nil
+	}
+	r.misses.Add(uint64(len(missing)))
+
+	generation := r.generation.Load()
+	fetched, err := r.next.FindByIDs(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(fetched))
+	for _, dvc := range fetched {
+		found[dvc.ID] = true
+		copied := dvc
+		r.devices.addIfGeneration("id:"+dvc.ID, &copied, generation, &r.generation)
+	}
+	for _, id := range missing {
+		if !found[id] {
+			r.devices.addIfGeneration("id:"+id, nil, generation, &r.generation)
+		}
+	}
+
+	devices = append(devices, fetched...)
+	device.SortDevices(devices)
+	return devices, nil
+}
+
+// List gets all devices. Listings are not cached.
+func (r *Repository) List(ctx context.Context) ([]device.Device, error) {
+	return r.next.List(ctx)
+}
+
+// ListPage gets one page of devices. Listings are not cached.
+func (r *Repository) ListPage(ctx context.Context, options device.ListOptions) (device.Page, error) {
+	return r.next.ListPage(ctx, options)
+}
+
+// Update updates a device and invalidates its cached entries.
+func (r *Repository) Update(ctx context.Context, dvc *device.Device) error {
+	defer r.invalidate(dvc.ID)
+	return r.next.Update(ctx, dvc)
+}
+
+// Remove deletes a device by its ID and invalidates its cached entries.
+func (r *Repository) Remove(ctx context.Context, id string) error {
+	defer r.invalidate(id)
+	return r.next.Remove(ctx, id)
+}
+
+// RemoveByIDs deletes the devices with the given IDs and invalidates their entries.
+func (r *Repository) RemoveByIDs(ctx context.Context, ids []string) ([]device.Device, error) {
+	defer func() {
+		for _, id := range ids {
+			r.invalidate(id)
+		}
+	}()
+	return r.next.RemoveByIDs(ctx, ids)
+}
+
+// FindByBrand gets a list of devices by brand, from the cache when possible.
+func (r *Repository) FindByBrand(ctx context.Context, brand string) ([]device.Device, error) {
+	devices, err := load(ctx, r, r.brands, "brand:"+brand, func(ctx context.Context) ([]device.Device, error) {
+		return r.next.FindByBrand(ctx, brand)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]device.Device(nil), devices...), nil
+}
+
+// CountByBrand gets the number of devices per brand, of the given brands only
+// when any are passed. Counts are not cached.
+func (r *Repository) CountByBrand(ctx context.Context, brands ...string) (map[string]int, error) {
+	return r.next.CountByBrand(ctx, brands...)
+}
+
+// Search finds devices matching query. Results are not cached.
+func (r *Repository) Search(ctx context.Context, query string, limit int) ([]device.SearchResult, error) {
+	return r.next.Search(ctx, query, limit)
+}
+
+// CreationHistogram counts the devices created per interval. Histograms are not cached.
+func (r *Repository) CreationHistogram(ctx context.Context, options device.HistogramOptions) ([]device.Bucket, error) {
+	return r.next.CreationHistogram(ctx, options)
+}
+
+// WithTx runs fn in a transaction of the wrapped repository. fn works on the
+// backend directly, so it reads its own writes, and every entry is dropped
+// once the transaction ends, since it may have changed any device.
+func (r *Repository) WithTx(ctx context.Context, options device.TxOptions, fn func(tx device.Repository) error) error {
+	transactor, ok := r.next.(device.Transactor)
+	if !ok {
+		return device.ErrTxUnsupported
+	}
+
+	defer func() {
+		r.devices.purge(&r.generation)
+		r.brands.purge(&r.generation)
+	}()
+	return transactor.WithTx(ctx, options, fn)
+}
+
+// invalidate drops the entry for id (if any) and every brand listing, since a
+// write may move a device between brands. It runs after the backend write so
+// loads started before the write cannot repopulate stale data: the generation
+// is bumped under the lock of each lru as its entries are dropped, and loads
+// add entries under that lock only if the generation they started with holds.
+func (r *Repository) invalidate(id string) {
+	if id != "" {
+		r.devices.remove("id:"+id, &r.generation)
+	}
+	r.brands.purge(&r.generation)
+}
+
+// load returns the cached value for key or fetches it once for all concurrent callers.
+// The shared fetch ignores the first caller's cancellation, so one client going
+// away does not fail the others waiting on the same flight.
+func load[V any](ctx context.Context, r *Repository, c *lru[V], key string, fetch func(context.Context) (V, error)) (V, error) {
+	if v, found := c.get(key); found {
+		r.hits.Add(1)
+		return v, nil
+	}
+	r.misses.Add(1)
+
+	generation := r.generation.Load()
+	flightKey := strconv.FormatUint(generation, 10) + "/" + key
+
+	v, err, _ := r.group.Do(flightKey, func() (any, error) {
+		v, err := fetch(context.WithoutCancel(ctx))
+		if err == nil {
+			c.addIfGeneration(key, v, generation, &r.generation)
+		}
+		return v, err
+	})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	return v.(V), nil
+}
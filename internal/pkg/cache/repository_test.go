@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device/devicetest"
+)
+
+// countingRepository counts backend lookups and can block them until released.
+type countingRepository struct {
+	device.MockRepository
+	findByID  atomic.Int32
+	findByIDs atomic.Int32
+	release   chan struct{}
+}
+
+func (r *countingRepository) FindByID(ctx context.Context, id string) (*device.Device, error) {
+	r.findByID.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	return r.MockRepository.FindByID(ctx, id)
+}
+
+func (r *countingRepository) FindByIDs(ctx context.Context, ids []string) ([]device.Device, error) {
+	r.findByIDs.Add(1)
+	return r.MockRepository.FindByIDs(ctx, ids)
+}
+
+func TestRepository_Conformance(t *testing.T) {
+	devicetest.RepositoryConformance(t, func(t *testing.T) device.Repository {
+		return New(&device.MockRepository{}, 100, time.Minute)
+	})
+}
+
+func TestRepository_FindByIDHitsCache(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingRepository{}
+	repo := New(backend, 100, time.Minute)
+
+	dvc := &device.Device{Name: "Pixel 8", Brand: "Google"}
+	require.NoError(t, repo.Store(ctx, dvc))
+
+	for i := 0; i < 3; i++ {
+		found, err := repo.FindByID(ctx, dvc.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Pixel 8", found.Name)
+	}
+
+	assert.Equal(t, int32(1), backend.findByID.Load())
+	stats := repo.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+}
+
+func TestRepository_FindByIDsFetchesOnlyMisses(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingRepository{}
+	repo := New(backend, 100, time.Minute)
+
+	first := &device.Device{Name: "Pixel 8", Brand: "Google"}
+	require.NoError(t, repo.Store(ctx, first))
+	time.Sleep(2 * time.Millisecond)
+	second := &device.Device{Name: "iPhone 15", Brand: "Apple"}
+	require.NoError(t, repo.Store(ctx, second))
+
+	_, err := repo.FindByID(ctx, second.ID)
+	require.NoError(t, err)
+
+	devices, err := repo.FindByIDs(ctx, []string{second.ID, first.ID, "missing"})
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+	assert.Equal(t, first.ID, devices[0].ID)
+	assert.Equal(t, second.ID, devices[1].ID)
+	assert.Equal(t, int32(1), backend.findByIDs.Load())
+
+	devices, err = repo.FindByIDs(ctx, []string{first.ID, "missing"})
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, int32(1), backend.findByIDs.Load(), "found devices and misses are cached")
+}
+
+func TestRepository_EntriesExpire(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingRepository{}
+	now := time.Now()
+	repo := newWithClock(backend, 100, time.Minute, func() time.Time { return now })
+
+	_, err := repo.FindByID(ctx, "1")
+	require.NoError(t, err)
+	_, err = repo.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), backend.findByID.Load())
+
+	now = now.Add(time.Minute)
+
+	_, err = repo.FindByID(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), backend.findByID.Load())
+}
+
+func TestRepository_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingRepository{}
+	repo := New(backend, 2, time.Minute)
+
+	for _, id := range []string{"1", "2", "1", "3", "1"} {
+		_, err := repo.FindByID(ctx, id)
+		require.NoError(t, err)
+	}
+
+	// "2" was the least recently used when "3" was added.
+	assert.Equal(t, int32(3), backend.findByID.Load())
+	assert.Equal(t, uint64(1), repo.Stats().Evictions)
+}
+
+func TestRepository_WritesInvalidate(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingRepository{}
+	repo := New(backend, 100, time.Minute)
+
+	dvc := &device.Device{Name: "Pixel 8", Brand: "Google"}
+	require.NoError(t, repo.Store(ctx, dvc))
+
+	_, err := repo.FindByID(ctx, dvc.ID)
+	require.NoError(t, err)
+	devices, err := repo.FindByBrand(ctx, "Google")
+	require.NoError(t, err)
+	assert.Len(t, devices, 1)
+
+	require.NoError(t, repo.Update(ctx, &device.Device{ID: dvc.ID, Brand: "Alphabet"}))
+
+	found, err := repo.FindByID(ctx, dvc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alphabet", found.Brand)
+
+	devices, err = repo.FindByBrand(ctx, "Google")
+	require.NoError(t, err)
+	assert.Empty(t, devices)
+
+	require.NoError(t, repo.Remove(ctx, dvc.ID))
+
+	found, err = repo.FindByID(ctx, dvc.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestRepository_TransactionsInvalidate(t *testing.T) {
+	ctx := context.Background()
+	repo := New(&device.MockRepository{}, 100, time.Minute)
+
+	dvc := &device.Device{Name: "Pixel 8", Brand: "Google"}
+	require.NoError(t, repo.Store(ctx, dvc))
+	_, err := repo.FindByID(ctx, dvc.ID)
+	require.NoError(t, err)
+
+	err = repo.WithTx(ctx, device.TxOptions{}, func(tx device.Repository) error {
+		return tx.Update(ctx, &device.Device{ID: dvc.ID, Name: "Pixel 8 Pro"})
+	})
+	require.NoError(t, err)
+
+	found, err := repo.FindByID(ctx, dvc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Pixel 8 Pro", found.Name)
+}
+
+func TestRepository_CollapsesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingRepository{release: make(chan struct{})}
+	repo := New(backend, 100, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.FindByID(ctx, "1")
+			assert.NoError(t, err)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), backend.findByID.Load())
+	assert.Equal(t, uint64(10), repo.Stats().Misses)
+}
+
+func TestLRU_AddsOnlyInTheLoadGeneration(t *testing.T) {
+	var generation atomic.Uint64
+	c := newLRU[int](10, time.Minute, time.Now)
+
+	gen := generation.Load()
+	c.remove("a", &generation)
+	c.addIfGeneration("a", 1, gen, &generation)
+	_, found := c.get("a")
+	assert.False(t, found, "a load started before the removal must not add its result")
+
+	gen = generation.Load()
+	c.addIfGeneration("a", 2, gen, &generation)
+	v, found := c.get("a")
+	assert.True(t, found)
+	assert.Equal(t, 2, v)
+
+	c.purge(&generation)
+	c.addIfGeneration("b", 3, gen, &generation)
+	assert.Zero(t, c.len())
+}
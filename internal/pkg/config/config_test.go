@@ -0,0 +1,263 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, found := values[name]
+		return value, found
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load(nil, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, `
+http:
+  port: 9000
+cache:
+  size: 50
+  ttl: 1m
+log:
+  level: debug
+`)
+
+	cfg, err := load(
+		[]string{"--config", path, "--cache-size", "70"},
+		env(map[string]string{"CACHE_SIZE": "60", "CACHE_TTL": "2m"}),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, 9000, cfg.HTTP.Port)
+	assert.Equal(t, 70, cfg.Cache.Size)
+	assert.Equal(t, 2*time.Minute, cfg.Cache.TTL)
+	assert.Equal(t, "debug", cfg.Log.Level)
+}
+
+func TestLoad_EnvAliases(t *testing.T) {
+	cfg, err := load(nil, env(map[string]string{"POSTGRES_CONN": "postgres://legacy/db"}))
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://legacy/db", cfg.Database.URL)
+
+	cfg, err = load(nil, env(map[string]string{"POSTGRES_CONN": "postgres://legacy/db", "DATABASE_URL": "sqlite://:memory:"}))
+	require.NoError(t, err)
+	assert.Equal(t, "sqlite://:memory:", cfg.Database.URL)
+}
+
+func TestLoad_ConfigFileFromEnv(t *testing.T) {
+	path := writeFile(t, "http:\n  port: 9100\n")
+
+	cfg, err := load(nil, env(map[string]string{"CONFIG_FILE": path}))
+	require.NoError(t, err)
+	assert.Equal(t, 9100, cfg.HTTP.Port)
+}
+
+func TestLoad_UnknownFileKey(t *testing.T) {
+	path := writeFile(t, "http:\n  prot: 9000\n")
+
+	_, err := load([]string{"--config", path}, env(nil))
+	assert.ErrorContains(t, err, "field prot not found")
+}
+
+func TestLoad_InvalidValues(t *testing.T) {
+	_, err := load([]string{"--http-port", "abc"}, env(map[string]string{"CACHE_TTL": "soon"}))
+	assert.EqualError(t, err, "flag --http-port: invalid integer \"abc\"\nenv CACHE_TTL: invalid duration \"soon\"")
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.HTTP.Port = 70000
+	cfg.Database.URL = "mysql://localhost/db"
+	cfg.Cache.Size = 100
+	cfg.Cache.TTL = 0
+	cfg.Log.Level = "loud"
+	cfg.Tracing.Exporter = "jaeger"
+
+	assert.EqualError(t, cfg.Validate(), `http.port: must be between 1 and 65535, got 70000
+database.url: unsupported scheme "mysql", expected postgres, postgresql or sqlite
+cache.ttl: must be positive when the cache is enabled, got 0s
+log.level: unknown level "loud"
+tracing.exporter: unknown exporter "jaeger", expected none, stdout or otlpfile`)
+}
+
+func TestDatabase_Backend(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"postgres://admin@db/devices":                "postgres",
+		"PostgreSQL://admin@db/devices":              "postgres",
+		"host=db user=admin password=a:b dbname=dev": "postgres",
+		"":                     "postgres",
+		"sqlite://:memory:":    "sqlite",
+		"mysql://localhost/db": "mysql",
+	} {
+		assert.Equal(t, want, Database{URL: dsn}.Backend(), dsn)
+	}
+
+	cfg := Default()
+	cfg.Database.URL = "host=db user=admin password=a:b dbname=devices"
+	cfg.RateLimit.Store = "postgres"
+	assert.NoError(t, cfg.Validate(), "key=value strings are PostgreSQL DSNs")
+}
+
+func TestValidate_GRPC(t *testing.T) {
+	cfg := Default()
+	cfg.GRPC.Port = cfg.HTTP.Port
+	assert.EqualError(t, cfg.Validate(), "grpc.port: must differ from http.port 8080")
+
+	cfg.GRPC.Port = -1
+	assert.EqualError(t, cfg.Validate(), "grpc.port: must be between 0 and 65535, got -1")
+
+	cfg.GRPC.Port = 0
+	assert.NoError(t, cfg.Validate(), "0 disables the gRPC server")
+}
+
+func TestValidate_HTTP(t *testing.T) {
+	cfg := Default()
+	cfg.HTTP.ReadHeaderTimeout = 0
+	cfg.HTTP.MaxBodyBytes = -1
+	cfg.HTTP.TLS.KeyFile = "server.key"
+	cfg.HTTP.TLS.ClientAuth = "require"
+
+	assert.EqualError(t, cfg.Validate(), `http.readHeaderTimeout: must be positive, got 0s
+http.maxBodyBytes: must not be negative, got -1
+http.tls: certFile and keyFile must be set together
+http.tls.clientAuth: require requires certFile and keyFile
+http.tls.clientAuth: require requires clientCAFile`)
+}
+
+func TestLoad_NestedSection(t *testing.T) {
+	cfg, err := load([]string{"--http-tls-cert-file", "server.crt"}, env(map[string]string{"HTTP_TLS_KEY_FILE": "server.key"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, TLS{CertFile: "server.crt", KeyFile: "server.key", ClientAuth: "none"}, cfg.HTTP.TLS)
+	assert.True(t, cfg.HTTP.TLS.Enabled())
+
+	var out bytes.Buffer
+	require.NoError(t, cfg.Print(&out))
+	assert.Contains(t, out.String(), "  tls:\n    certFile: server.crt\n")
+}
+
+func TestValidate_APIKeys(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.APIKeys = []string{"ci:k1", "nokey", "ci:k2", "ops:k1"}
+
+	assert.EqualError(t, cfg.Validate(), `auth.apiKeys: entry 2 must be a name:key pair
+auth.apiKeys: duplicate name "ci"
+auth.apiKeys: entry 4 reuses the key of another entry`)
+}
+
+func TestAuth_Principals(t *testing.T) {
+	cfg, err := load(nil, env(map[string]string{"AUTH_API_KEYS": "ci:k1, ops:k2:with-colon"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"k1": "ci", "k2:with-colon": "ops"}, cfg.Auth.Principals())
+}
+
+func TestValidate_RateLimit(t *testing.T) {
+	cfg := Default()
+	cfg.Database.URL = "sqlite://:memory:"
+	cfg.RateLimit.Requests = -1
+	cfg.RateLimit.Period = 0
+	cfg.RateLimit.Routes = []string{"/devices=10"}
+	cfg.RateLimit.Store = "postgres"
+
+	assert.EqualError(t, cfg.Validate(), `rateLimit.requests: must not be negative, got -1
+rateLimit.period: must be positive, got 0s
+rateLimit.routes: route limit "/devices=10" must look like "METHOD /route=requests"
+rateLimit.store: postgres requires a PostgreSQL database`)
+}
+
+func TestRateLimit_RouteLimits(t *testing.T) {
+	cfg, err := load([]string{"--rate-limit-routes", "GET /devices=30, delete /devices/:id = 0"}, env(nil))
+	require.NoError(t, err)
+
+	limits, err := cfg.RateLimit.RouteLimits()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"GET /devices": 30, "DELETE /devices/:id": 0}, limits)
+
+	_, err = RateLimit{Routes: []string{"GET /devices=many"}}.RouteLimits()
+	assert.EqualError(t, err, `route limit "GET /devices=many" must have a non-negative number of requests`)
+}
+
+func TestHTTP_CacheControlRoutes(t *testing.T) {
+	cfg, err := load([]string{"--http-cache-control", "GET /devices/:id=private  max-age=60, get /devices/=no-store"}, env(nil))
+	require.NoError(t, err)
+
+	routes, err := cfg.HTTP.CacheControlRoutes()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"GET /devices/:id": "private, max-age=60", "GET /devices": "no-store"}, routes)
+
+	cfg.HTTP.CacheControl = []string{"/devices=no-store"}
+	assert.EqualError(t, cfg.Validate(), `http.cacheControl: cache control "/devices=no-store" must look like "METHOD /route=directives"`)
+}
+
+func TestValidate_TrustedProxies(t *testing.T) {
+	cfg, err := load([]string{"--http-trusted-proxies", "10.0.0.0/8,192.0.2.1"}, env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.0.2.1"}, cfg.HTTP.TrustedProxies)
+
+	cfg.HTTP.TrustedProxies = []string{"proxy.internal"}
+	assert.EqualError(t, cfg.Validate(), `http.trustedProxies: "proxy.internal" is neither an IP nor a CIDR`)
+}
+
+func TestHTTP_LegacySunsetTime(t *testing.T) {
+	sunset, err := Default().HTTP.LegacySunsetTime()
+	require.NoError(t, err)
+	assert.True(t, sunset.IsZero(), "no sunset is announced unless configured")
+
+	sunset, err = HTTP{LegacySunset: "2027-04-30"}.LegacySunsetTime()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC), sunset)
+
+	cfg := Default()
+	cfg.HTTP.LegacySunset = "30/04/2027"
+	assert.EqualError(t, cfg.Validate(), `http.legacySunset: must be a YYYY-MM-DD date, got "30/04/2027"`)
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.URL = "postgres://admin:s3cret@db:5432/devices"
+	cfg.Auth.APIKeys = []string{"ci:t0ps3cret"}
+
+	var out bytes.Buffer
+	require.NoError(t, cfg.Print(&out))
+
+	assert.Contains(t, out.String(), "url: postgres://admin:xxxxx@db:5432/devices")
+	assert.NotContains(t, out.String(), "s3cret")
+	assert.Contains(t, out.String(), "apiKeys: '[REDACTED]'")
+	assert.NotContains(t, out.String(), "t0ps3cret")
+	assert.Contains(t, out.String(), "ttl: 10s")
+}
+
+func TestRedactDSN(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"postgres://admin:s3cret@db/devices":                               "postgres://admin:xxxxx@db/devices",
+		"postgres://db/devices?user=admin&password=s3cret&sslmode=require": "postgres://db/devices?user=admin&password=xxxxx&sslmode=require",
+		"postgres://db/devices?sslpassword=s3cret":                         "postgres://db/devices?sslpassword=xxxxx",
+		"host=db user=admin password=s3cret dbname=devices":                "host=db user=admin password=xxxxx dbname=devices",
+		"host=db password = 's3 cret' sslpassword=s3cret":                  "host=db password = xxxxx sslpassword=xxxxx",
+		"sqlite://devices.db":                                              "sqlite://devices.db",
+		"host=db password=a:b":                                             "host=db password=xxxxx",
+	} {
+		assert.Equal(t, want, redactDSN(dsn), dsn)
+	}
+}
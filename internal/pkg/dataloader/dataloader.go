@@ -0,0 +1,115 @@
+// Package dataloader batches and caches the lookups made while serving one
+// request, so resolving a field for many objects costs one backend call
+// instead of one per object.
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchFunc fetches the values for keys in one call. Keys it returns no value
+// for are reported as not found.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader collects the keys requested within a short window and fetches them
+// with a single BatchFunc call. Results, errors included, are cached for the
+// life of the loader, which should therefore be created per request.
+type Loader[K comparable, V any] struct {
+	fetch    BatchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	results map[K]*result[V]
+	pending *batch[K, V]
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	found bool
+	err   error
+}
+
+type batch[K comparable, V any] struct {
+	ctx   context.Context
+	keys  []K
+	timer *time.Timer
+}
+
+// New returns a loader that waits up to wait for more keys before fetching,
+// and fetches early once maxBatch keys are pending (0 for no limit).
+func New[K comparable, V any](fetch BatchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:    fetch,
+		wait:     wait,
+		maxBatch: maxBatch,
+		results:  make(map[K]*result[V]),
+	}
+}
+
+// Load returns the value for key, and whether the batch function found one.
+// The batch is fetched with the context of its first Load, detached from its
+// cancellation, so one caller going away does not fail the others.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, bool, error) {
+	l.mu.Lock()
+	r, found := l.results[key]
+	if !found {
+		r = &result[V]{done: make(chan struct{})}
+		l.results[key] = r
+		l.enqueue(ctx, key)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.value, r.found, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, false, ctx.Err()
+	}
+}
+
+// enqueue adds key to the pending batch, starting one if needed. l.mu must be held.
+func (l *Loader[K, V]) enqueue(ctx context.Context, key K) {
+	if l.pending == nil {
+		b := &batch[K, V]{ctx: context.WithoutCancel(ctx)}
+		b.timer = time.AfterFunc(l.wait, func() { l.dispatch(b) })
+		l.pending = b
+	}
+
+	l.pending.keys = append(l.pending.keys, key)
+	if l.maxBatch > 0 && len(l.pending.keys) >= l.maxBatch {
+		b := l.pending
+		l.pending = nil
+		// If the timer already fired, its dispatch is under way.
+		if b.timer.Stop() {
+			go l.dispatch(b)
+		}
+	}
+}
+
+// dispatch fetches b and hands its results to the waiting loads.
+func (l *Loader[K, V]) dispatch(b *batch[K, V]) {
+	l.mu.Lock()
+	if l.pending == b {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+
+	values, err := l.fetch(b.ctx, b.keys)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range b.keys {
+		r := l.results[key]
+		if err != nil {
+			r.err = err
+		} else {
+			r.value, r.found = values[key]
+		}
+		close(r.done)
+	}
+}
@@ -0,0 +1,107 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a batch function that returns the square of every positive key
+// and records the batches it was called with.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+	err     error
+}
+
+func (r *recorder) fetch(_ context.Context, keys []int) (map[int]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sorted := append([]int(nil), keys...)
+	sort.Ints(sorted)
+	r.batches = append(r.batches, sorted)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	values := make(map[int]int)
+	for _, k := range keys {
+		if k > 0 {
+			values[k] = k * k
+		}
+	}
+	return values, nil
+}
+
+// loadAll loads keys concurrently.
+func loadAll(t *testing.T, loader *Loader[int, int], keys ...int) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for _, k := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _ = loader.Load(context.Background(), k)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLoader_BatchesConcurrentLoads(t *testing.T) {
+	r := &recorder{}
+	loader := New(r.fetch, 10*time.Millisecond, 0)
+
+	loadAll(t, loader, 1, 2, 3, 2)
+
+	assert.Equal(t, [][]int{{1, 2, 3}}, r.batches)
+
+	value, found, err := loader.Load(context.Background(), 3)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 9, value)
+	assert.Len(t, r.batches, 1, "results are cached")
+}
+
+func TestLoader_ReportsMissingKeys(t *testing.T) {
+	loader := New((&recorder{}).fetch, time.Millisecond, 0)
+
+	_, found, err := loader.Load(context.Background(), -1)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestLoader_SplitsLargeBatches(t *testing.T) {
+	r := &recorder{}
+	loader := New(r.fetch, time.Hour, 2)
+
+	loadAll(t, loader, 1, 2, 3, 4)
+
+	require.Len(t, r.batches, 2)
+	for _, b := range r.batches {
+		assert.Len(t, b, 2)
+	}
+}
+
+func TestLoader_SharesErrors(t *testing.T) {
+	r := &recorder{err: errors.New("connection refused")}
+	loader := New(r.fetch, time.Millisecond, 0)
+
+	_, _, err := loader.Load(context.Background(), 1)
+	assert.EqualError(t, err, "connection refused")
+}
+
+func TestLoader_StopsWaitingWhenContextIsDone(t *testing.T) {
+	loader := New((&recorder{}).fetch, time.Hour, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := loader.Load(ctx, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+}
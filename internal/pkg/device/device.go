@@ -1,6 +1,19 @@
 package device
 
-import "time"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when an operation targets a device that does not exist.
+	ErrNotFound = errors.New("device not found")
+	// ErrMissingID is returned when an operation requires a device id and none was given.
+	ErrMissingID = errors.New("device id is required")
+	// ErrInvalidUpdate is returned when an update request has no fields to change.
+	ErrInvalidUpdate = errors.New("invalid update request")
+)
 
 // Device represents the device entity data structure.
 type Device struct {
@@ -12,11 +25,32 @@ type Device struct {
 }
 
 // Repository is an interface for devices dataset.
+//
+// Implementations assign the ID and timestamps on Store. FindByID returns a
+// nil device (and no error) when it misses, and FindByIDs returns the devices
+// found among the given ids, in listing order, skipping missing ones. List
+// orders devices by creation time, and ListPage filters and paginates in the
+// same order, returning ErrInvalidPageToken for malformed tokens. Update
+// applies partial updates, and Update and Remove return ErrNotFound for a
+// missing device. RemoveByIDs deletes the devices among the given ids in one
+// operation and returns them as they were, in listing order. CountByBrand
+// counts the devices of every brand, or only of the given brands when any are
+// passed, leaving out brands without devices. Search returns the devices
+// whose name or brand match the query, best first, at most limit of them when
+// limit is positive. CreationHistogram counts the devices created in the
+// options' range per interval, with a bucket for every interval, empty ones
+// included. devicetest.RepositoryConformance verifies these semantics.
 type Repository interface {
-	Store(device *Device) error
-	FindByID(id string) (*Device, error)
-	List() ([]Device, error)
-	Update(device *Device) error
-	Remove(id string) error
-	FindByBrand(brand string) ([]Device, error)
+	Store(ctx context.Context, device *Device) error
+	FindByID(ctx context.Context, id string) (*Device, error)
+	FindByIDs(ctx context.Context, ids []string) ([]Device, error)
+	List(ctx context.Context) ([]Device, error)
+	ListPage(ctx context.Context, options ListOptions) (Page, error)
+	Update(ctx context.Context, device *Device) error
+	Remove(ctx context.Context, id string) error
+	RemoveByIDs(ctx context.Context, ids []string) ([]Device, error)
+	FindByBrand(ctx context.Context, brand string) ([]Device, error)
+	CountByBrand(ctx context.Context, brands ...string) (map[string]int, error)
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+	CreationHistogram(ctx context.Context, options HistogramOptions) ([]Bucket, error)
 }
@@ -0,0 +1,462 @@
+// Package devicetest holds the behavior every device.Repository
+// implementation must satisfy, for their tests to run.
+package devicetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+)
+
+// RepositoryConformance runs the behavior every device.Repository implementation must satisfy.
+// newRepo is called once per subtest and must return an empty repository.
+func RepositoryConformance(t *testing.T, newRepo func(t *testing.T) device.Repository) {
+	ctx := context.Background()
+
+	t.Run("StoreAssignsIDAndTimestamps", func(t *testing.T) {
+		repo := newRepo(t)
+
+		before := time.Now().Add(-time.Second)
+		dvc := &device.Device{Name: "Galaxy S23", Brand: "Samsung"}
+		require.NoError(t, repo.Store(ctx, dvc))
+
+		assert.NotEmpty(t, dvc.ID)
+		assert.True(t, dvc.CreationTime.After(before))
+		assert.True(t, dvc.UpdateTime.Equal(dvc.CreationTime))
+
+		other := &device.Device{Name: "Galaxy S24", Brand: "Samsung"}
+		require.NoError(t, repo.Store(ctx, other))
+		assert.NotEqual(t, dvc.ID, other.ID)
+	})
+
+	t.Run("FindByIDReturnsStoredDevice", func(t *testing.T) {
+		repo := newRepo(t)
+
+		dvc := &device.Device{Name: "Pixel 8", Brand: "Google"}
+		require.NoError(t, repo.Store(ctx, dvc))
+
+		found, err := repo.FindByID(ctx, dvc.ID)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assertSameDevice(t, *dvc, *found)
+	})
+
+	t.Run("FindByIDMissingReturnsNil", func(t *testing.T) {
+		repo := newRepo(t)
+
+		found, err := repo.FindByID(ctx, "missing")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+	})
+
+	t.Run("FindByIDsReturnsFoundDevicesInOrder", func(t *testing.T) {
+		repo := newRepo(t)
+
+		stored := storeDevices(t, repo,
+			device.Device{Name: "iPhone 15", Brand: "Apple"},
+			device.Device{Name: "Pixel 8", Brand: "Google"},
+			device.Device{Name: "iPhone 14", Brand: "Apple"},
+		)
+
+		devices, err := repo.FindByIDs(ctx, []string{stored[2].ID, "missing", stored[0].ID, stored[2].ID})
+		require.NoError(t, err)
+		require.Len(t, devices, 2)
+		assertSameDevice(t, stored[0], devices[0])
+		assertSameDevice(t, stored[2], devices[1])
+
+		devices, err = repo.FindByIDs(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, devices)
+	})
+
+	t.Run("ListEmpty", func(t *testing.T) {
+		repo := newRepo(t)
+
+		devices, err := repo.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, devices)
+	})
+
+	t.Run("ListOrderedByCreationTime", func(t *testing.T) {
+		repo := newRepo(t)
+
+		stored := storeDevices(t, repo,
+			device.Device{Name: "iPhone 15", Brand: "Apple"},
+			device.Device{Name: "Pixel 8", Brand: "Google"},
+			device.Device{Name: "iPhone 14", Brand: "Apple"},
+		)
+
+		devices, err := repo.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, devices, len(stored))
+		for i := range stored {
+			assertSameDevice(t, stored[i], devices[i])
+		}
+	})
+
+	t.Run("FindByBrandFiltersExactly", func(t *testing.T) {
+		repo := newRepo(t)
+
+		stored := storeDevices(t, repo,
+			device.Device{Name: "iPhone 15", Brand: "Apple"},
+			device.Device{Name: "Pixel 8", Brand: "Google"},
+			device.Device{Name: "iPhone 14", Brand: "Apple"},
+			device.Device{Name: "Apple Watch", Brand: "apple"},
+		)
+
+		devices, err := repo.FindByBrand(ctx, "Apple")
+		require.NoError(t, err)
+		require.Len(t, devices, 2)
+		assertSameDevice(t, stored[0], devices[0])
+		assertSameDevice(t, stored[2], devices[1])
+
+		devices, err = repo.FindByBrand(ctx, "Nokia")
+		require.NoError(t, err)
+		assert.Empty(t, devices)
+	})
+
+	t.Run("CountByBrand", func(t *testing.T) {
+		repo := newRepo(t)
+
+		counts, err := repo.CountByBrand(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, counts)
+
+		storeDevices(t, repo,
+			device.Device{Name: "iPhone 15", Brand: "Apple"},
+			device.Device{Name: "Pixel 8", Brand: "Google"},
+			device.Device{Name: "iPhone 14", Brand: "Apple"},
+		)
+
+		counts, err = repo.CountByBrand(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"Apple": 2, "Google": 1}, counts)
+
+		counts, err = repo.CountByBrand(ctx, "Apple", "Nokia")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"Apple": 2}, counts, "only the given brands are counted")
+	})
+
+	t.Run("SearchRanksPrefixAndFuzzyMatches", func(t *testing.T) {
+		repo := newRepo(t)
+
+		stored := storeDevices(t, repo,
+			device.Device{Name: "iPhone 15", Brand: "Apple"},
+			device.Device{Name: "Galaxy S23", Brand: "Samsung"},
+			device.Device{Name: "Galaxy Tab S9", Brand: "Samsung"},
+		)
+
+		results, err := repo.Search(ctx, "galaxy s2", 0)
+		require.NoError(t, err)
+		require.NotEmpty(t, results)
+		assertSameDevice(t, stored[1], results[0].Device)
+		assert.Positive(t, results[0].Score)
+		assert.Equal(t, "<mark>Galaxy</mark> <mark>S23</mark>", results[0].Highlights.Name)
+		for _, r := range results {
+			assert.NotEqual(t, stored[0].ID, r.Device.ID)
+		}
+
+		results, err = repo.Search(ctx, "galxy", 0)
+		require.NoError(t, err, "a misspelled term still matches")
+		require.Len(t, results, 2)
+		assert.Contains(t, results[0].Highlights.Name, "<mark>Galaxy</mark>")
+
+		results, err = repo.Search(ctx, "samsung", 1)
+		require.NoError(t, err)
+		assert.Len(t, results, 1)
+
+		results, err = repo.Search(ctx, "nokia", 0)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("CreationHistogram", func(t *testing.T) {
+		repo := newRepo(t)
+
+		stored := storeDevices(t, repo,
+			device.Device{Name: "iPhone 15", Brand: "Apple"},
+			device.Device{Name: "Pixel 8", Brand: "Google"},
+		)
+		created := stored[0].CreationTime
+
+		for _, interval := range []device.Interval{device.Day, device.Week, device.Month} {
+			options := device.HistogramOptions{Interval: interval, From: created.AddDate(0, -2, 0), To: stored[1].CreationTime.Add(time.Microsecond)}
+			buckets, err := repo.CreationHistogram(ctx, options)
+			require.NoError(t, err, interval)
+			require.NotEmpty(t, buckets, interval)
+
+			assert.Equal(t, interval.Truncate(options.From), buckets[0].Start, interval)
+			last := buckets[len(buckets)-1]
+			assert.Equal(t, interval.Truncate(created), last.Start, interval)
+			assert.Equal(t, 2, last.Count, interval)
+			for _, b := range buckets[:len(buckets)-1] {
+				assert.Zero(t, b.Count, interval)
+			}
+		}
+
+		buckets, err := repo.CreationHistogram(ctx, device.HistogramOptions{Interval: device.Day, From: created.Add(-time.Hour), To: created})
+		require.NoError(t, err)
+		for _, b := range buckets {
+			assert.Zero(t, b.Count, "To is exclusive")
+		}
+
+		_, err = repo.CreationHistogram(ctx, device.HistogramOptions{Interval: "year", From: created, To: created.Add(time.Hour)})
+		assert.ErrorIs(t, err, device.ErrInvalidInterval)
+	})
+
+	t.Run("ListPagePaginates", func(t *testing.T) {
+		repo := newRepo(t)
+
+		stored := storeDevices(t, repo,
+			device.Device{Name: "iPhone 15", Brand: "Apple"},
+			device.Device{Name: "Pixel 8", Brand: "Google"},
+			device.Device{Name: "iPhone 14", Brand: "Apple"},
+			device.Device{Name: "Galaxy S23", Brand: "Samsung"},
+			device.Device{Name: "iPhone 13", Brand: "Apple"},
+		)
+
+		var listed []device.Device
+		options := device.ListOptions{PageSize: 2}
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 3, "listing did not terminate")
+
+			page, err := repo.ListPage(ctx, options)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page.Devices), 2)
+			listed = append(listed, page.Devices...)
+
+			if page.NextPageToken == "" {
+				break
+			}
+			options.PageToken = page.NextPageToken
+		}
+
+		require.Len(t, listed, len(stored))
+		for i := range stored {
+			assertSameDevice(t, stored[i], listed[i])
+		}
+
+		all, err := repo.ListPage(ctx, device.ListOptions{})
+		require.NoError(t, err)
+		assert.Len(t, all.Devices, len(stored))
+		assert.Empty(t, all.NextPageToken)
+	})
+
+	t.Run("ListPageFiltersByBrand", func(t *testing.T) {
+		repo := newRepo(t)
+
+		stored := storeDevices(t, repo,
+			device.Device{Name: "iPhone 15", Brand: "Apple"},
+			device.Device{Name: "Pixel 8", Brand: "Google"},
+			device.Device{Name: "iPhone 14", Brand: "Apple"},
+		)
+
+		page, err := repo.ListPage(ctx, device.ListOptions{Brand: "Apple", PageSize: 1})
+		require.NoError(t, err)
+		require.Len(t, page.Devices, 1)
+		assertSameDevice(t, stored[0], page.Devices[0])
+		require.NotEmpty(t, page.NextPageToken)
+
+		page, err = repo.ListPage(ctx, device.ListOptions{Brand: "Apple", PageSize: 1, PageToken: page.NextPageToken})
+		require.NoError(t, err)
+		require.Len(t, page.Devices, 1)
+		assertSameDevice(t, stored[2], page.Devices[0])
+		assert.Empty(t, page.NextPageToken)
+	})
+
+	t.Run("ListPageLoadsSelectedFields", func(t *testing.T) {
+		repo := newRepo(t)
+
+		stored := storeDevices(t, repo,
+			device.Device{Name: "iPhone 15", Brand: "Apple"},
+			device.Device{Name: "Pixel 8", Brand: "Google"},
+		)
+
+		page, err := repo.ListPage(ctx, device.ListOptions{Fields: []string{"name"}, PageSize: 1})
+		require.NoError(t, err)
+		require.Len(t, page.Devices, 1)
+		assert.Equal(t, stored[0].ID, page.Devices[0].ID)
+		assert.Equal(t, stored[0].Name, page.Devices[0].Name)
+		assert.True(t, stored[0].CreationTime.Equal(page.Devices[0].CreationTime), "IDs and timestamps are always loaded")
+		assert.True(t, stored[0].UpdateTime.Equal(page.Devices[0].UpdateTime), "IDs and timestamps are always loaded")
+
+		page, err = repo.ListPage(ctx, device.ListOptions{Fields: []string{"brand"}, PageToken: page.NextPageToken})
+		require.NoError(t, err)
+		require.Len(t, page.Devices, 1)
+		assert.Equal(t, stored[1].ID, page.Devices[0].ID)
+		assert.Equal(t, stored[1].Brand, page.Devices[0].Brand)
+	})
+
+	t.Run("ListPageRejectsInvalidToken", func(t *testing.T) {
+		repo := newRepo(t)
+
+		_, err := repo.ListPage(ctx, device.ListOptions{PageToken: "not-a-token"})
+		assert.ErrorIs(t, err, device.ErrInvalidPageToken)
+	})
+
+	t.Run("UpdateAppliesPartialChanges", func(t *testing.T) {
+		repo := newRepo(t)
+
+		dvc := &device.Device{Name: "Pixel 8", Brand: "Google"}
+		require.NoError(t, repo.Store(ctx, dvc))
+
+		time.Sleep(2 * time.Millisecond)
+		require.NoError(t, repo.Update(ctx, &device.Device{ID: dvc.ID, Name: "Pixel 8 Pro"}))
+
+		found, err := repo.FindByID(ctx, dvc.ID)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.Equal(t, "Pixel 8 Pro", found.Name)
+		assert.Equal(t, "Google", found.Brand)
+		assert.True(t, found.CreationTime.Equal(dvc.CreationTime))
+		assert.True(t, found.UpdateTime.After(dvc.UpdateTime))
+
+		require.NoError(t, repo.Update(ctx, &device.Device{ID: dvc.ID, Brand: "Alphabet"}))
+
+		found, err = repo.FindByID(ctx, dvc.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Pixel 8 Pro", found.Name)
+		assert.Equal(t, "Alphabet", found.Brand)
+
+		require.NoError(t, repo.Update(ctx, &device.Device{ID: dvc.ID, Name: "Pixel 9", Brand: "Google"}))
+
+		found, err = repo.FindByID(ctx, dvc.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Pixel 9", found.Name)
+		assert.Equal(t, "Google", found.Brand)
+	})
+
+	t.Run("UpdateErrors", func(t *testing.T) {
+		repo := newRepo(t)
+
+		dvc := &device.Device{Name: "Pixel 8", Brand: "Google"}
+		require.NoError(t, repo.Store(ctx, dvc))
+
+		assert.ErrorIs(t, repo.Update(ctx, &device.Device{Name: "Pixel 9"}), device.ErrMissingID)
+		assert.ErrorIs(t, repo.Update(ctx, &device.Device{ID: dvc.ID}), device.ErrInvalidUpdate)
+		assert.ErrorIs(t, repo.Update(ctx, &device.Device{ID: "missing", Name: "Pixel 9"}), device.ErrNotFound)
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		repo := newRepo(t)
+
+		stored := storeDevices(t, repo,
+			device.Device{Name: "Pixel 8", Brand: "Google"},
+			device.Device{Name: "iPhone 15", Brand: "Apple"},
+		)
+
+		require.NoError(t, repo.Remove(ctx, stored[0].ID))
+
+		found, err := repo.FindByID(ctx, stored[0].ID)
+		require.NoError(t, err)
+		assert.Nil(t, found)
+
+		devices, err := repo.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, devices, 1)
+		assertSameDevice(t, stored[1], devices[0])
+
+		assert.ErrorIs(t, repo.Remove(ctx, stored[0].ID), device.ErrNotFound)
+	})
+
+	t.Run("WithTxCommitsOrRollsBack", func(t *testing.T) {
+		repo := newRepo(t)
+		transactor, ok := repo.(device.Transactor)
+		if !ok {
+			t.Skip("repository does not implement device.Transactor")
+		}
+
+		stored := storeDevices(t, repo, device.Device{Name: "Pixel 8", Brand: "Google"})
+
+		err := transactor.WithTx(ctx, device.TxOptions{Isolation: device.Serializable}, func(tx device.Repository) error {
+			if err := tx.Update(ctx, &device.Device{ID: stored[0].ID, Name: "Pixel 8 Pro"}); err != nil {
+				return err
+			}
+			return tx.Store(ctx, &device.Device{Name: "iPhone 15", Brand: "Apple"})
+		})
+		require.NoError(t, err)
+
+		devices, err := repo.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, devices, 2)
+		assert.Equal(t, "Pixel 8 Pro", devices[0].Name)
+
+		failure := errors.New("failure")
+		err = transactor.WithTx(ctx, device.TxOptions{}, func(tx device.Repository) error {
+			require.NoError(t, tx.Remove(ctx, stored[0].ID))
+			found, err := tx.FindByID(ctx, stored[0].ID)
+			require.NoError(t, err)
+			assert.Nil(t, found, "reads see the transaction's writes")
+
+			require.NoError(t, tx.(device.Transactor).WithTx(ctx, device.TxOptions{}, func(nested device.Repository) error {
+				return nested.Store(ctx, &device.Device{Name: "Galaxy S23", Brand: "Samsung"})
+			}))
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+
+		after, err := repo.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, after, 2, "rolled back, nested transaction included")
+		assertSameDevice(t, devices[0], after[0])
+		assertSameDevice(t, devices[1], after[1])
+
+		err = transactor.WithTx(ctx, device.TxOptions{Isolation: "read uncommitted; DROP TABLE devices"}, func(tx device.Repository) error {
+			t.Error("fn must not run with an invalid isolation level")
+			return nil
+		})
+		assert.ErrorIs(t, err, device.ErrInvalidIsolation)
+	})
+
+	t.Run("RemoveByIDsReturnsRemovedDevicesInOrder", func(t *testing.T) {
+		repo := newRepo(t)
+
+		stored := storeDevices(t, repo,
+			device.Device{Name: "Pixel 8", Brand: "Google"},
+			device.Device{Name: "iPhone 15", Brand: "Apple"},
+			device.Device{Name: "Galaxy S23", Brand: "Samsung"},
+		)
+
+		removed, err := repo.RemoveByIDs(ctx, []string{stored[2].ID, "missing", stored[0].ID})
+		require.NoError(t, err)
+		require.Len(t, removed, 2)
+		assertSameDevice(t, stored[0], removed[0])
+		assertSameDevice(t, stored[2], removed[1])
+
+		devices, err := repo.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, devices, 1)
+		assertSameDevice(t, stored[1], devices[0])
+
+		removed, err = repo.RemoveByIDs(ctx, []string{stored[0].ID})
+		require.NoError(t, err)
+		assert.Empty(t, removed)
+	})
+}
+
+// storeDevices stores the devices in order, spacing them out so creation times are distinct.
+func storeDevices(t *testing.T, repo device.Repository, devices ...device.Device) []device.Device {
+	ctx := context.Background()
+	t.Helper()
+	for i := range devices {
+		if i > 0 {
+			time.Sleep(2 * time.Millisecond)
+		}
+		require.NoError(t, repo.Store(ctx, &devices[i]))
+	}
+	return devices
+}
+
+func assertSameDevice(t *testing.T, expected, actual device.Device) {
+	t.Helper()
+	assert.Equal(t, expected.ID, actual.ID)
+	assert.Equal(t, expected.Name, actual.Name)
+	assert.Equal(t, expected.Brand, actual.Brand)
+	assert.True(t, expected.CreationTime.Equal(actual.CreationTime), "creation time: expected %v, got %v", expected.CreationTime, actual.CreationTime)
+	assert.True(t, expected.UpdateTime.Equal(actual.UpdateTime), "update time: expected %v, got %v", expected.UpdateTime, actual.UpdateTime)
+}
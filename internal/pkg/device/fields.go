@@ -0,0 +1,55 @@
+package device
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidField is returned when a field list names something that is not a device field.
+var ErrInvalidField = errors.New("invalid field, expected id, name, brand, creationTime or updateTime")
+
+// Fields lists the device fields by their JSON names, in response order.
+var Fields = []string{"id", "name", "brand", "creationTime", "updateTime"}
+
+// ParseFields validates a comma-separated field list, dropping duplicates.
+// The empty list selects every field and yields nil.
+func ParseFields(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(Fields, field) {
+			return nil, ErrInvalidField
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// Selects reports whether a field list returned by ParseFields includes field.
+func Selects(fields []string, field string) bool {
+	return len(fields) == 0 || slices.Contains(fields, field)
+}
+
+// Project returns the selected fields of d keyed by their JSON names.
+func Project(d Device, fields []string) map[string]any {
+	values := map[string]any{
+		"id":           d.ID,
+		"name":         d.Name,
+		"brand":        d.Brand,
+		"creationTime": d.CreationTime,
+		"updateTime":   d.UpdateTime,
+	}
+	for field := range values {
+		if !Selects(fields, field) {
+			delete(values, field)
+		}
+	}
+	return values
+}
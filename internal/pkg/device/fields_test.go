@@ -0,0 +1,32 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFields(t *testing.T) {
+	fields, err := ParseFields("")
+	require.NoError(t, err)
+	assert.Nil(t, fields)
+
+	fields, err = ParseFields(" name, id,name ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name", "id"}, fields)
+
+	_, err = ParseFields("id,serial")
+	assert.ErrorIs(t, err, ErrInvalidField)
+	_, err = ParseFields("id,")
+	assert.ErrorIs(t, err, ErrInvalidField)
+}
+
+func TestProject(t *testing.T) {
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	d := Device{ID: "1", Name: "Pixel 8", Brand: "Google", CreationTime: created, UpdateTime: created}
+
+	assert.Equal(t, map[string]any{"id": "1", "name": "Pixel 8"}, Project(d, []string{"id", "name"}))
+	assert.Len(t, Project(d, nil), len(Fields))
+}
@@ -1,21 +1,45 @@
 package device
 
-import "errors"
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+)
 
 // MockRepository implements device.Repository for testing.
 type MockRepository struct {
 	Devices []Device
 	Err     error
+
+	nextID int
 }
 
-func (m *MockRepository) List() ([]Device, error) {
+func (m *MockRepository) List(ctx context.Context) ([]Device, error) {
 	if m.Err != nil {
 		return nil, m.Err
 	}
-	return m.Devices, nil
+	return m.sorted(func(Device) bool { return true }), nil
+}
+
+func (m *MockRepository) ListPage(ctx context.Context, options ListOptions) (Page, error) {
+	if m.Err != nil {
+		return Page{}, m.Err
+	}
+	cursor, err := ParseCursor(options.PageToken)
+	if err != nil {
+		return Page{}, err
+	}
+	devices := m.sorted(func(d Device) bool {
+		return (options.Brand == "" || d.Brand == options.Brand) && (cursor == nil || cursor.After(d))
+	})
+	if options.PageSize > 0 && len(devices) > options.PageSize+1 {
+		devices = devices[:options.PageSize+1]
+	}
+	return NewPage(devices, options.PageSize), nil
 }
 
-func (m *MockRepository) FindByID(id string) (*Device, error) {
+func (m *MockRepository) FindByID(ctx context.Context, id string) (*Device, error) {
 	if m.Err != nil {
 		return nil, m.Err
 	}
@@ -27,41 +51,103 @@ func (m *MockRepository) FindByID(id string) (*Device, error) {
 	return nil, nil
 }
 
-func (m *MockRepository) FindByBrand(brand string) ([]Device, error) {
+func (m *MockRepository) FindByIDs(ctx context.Context, ids []string) ([]Device, error) {
 	if m.Err != nil {
 		return nil, m.Err
 	}
-	var results []Device
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	return m.sorted(func(d Device) bool { return wanted[d.ID] }), nil
+}
+
+func (m *MockRepository) FindByBrand(ctx context.Context, brand string) ([]Device, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.sorted(func(d Device) bool { return d.Brand == brand }), nil
+}
+
+func (m *MockRepository) CountByBrand(ctx context.Context, brands ...string) (map[string]int, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	counts := make(map[string]int)
 	for _, d := range m.Devices {
-		if d.Brand == brand {
-			results = append(results, d)
+		if len(brands) == 0 || slices.Contains(brands, d.Brand) {
+			counts[d.Brand]++
+		}
+	}
+	return counts, nil
+}
+
+func (m *MockRepository) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return SearchDevices(m.sorted(func(Device) bool { return true }), query, limit), nil
+}
+
+func (m *MockRepository) CreationHistogram(ctx context.Context, options HistogramOptions) ([]Bucket, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	if _, err := ParseInterval(string(options.Interval)); err != nil {
+		return nil, err
+	}
+	var buckets []Bucket
+	for _, d := range m.sorted(func(d Device) bool {
+		return !d.CreationTime.Before(options.From) && d.CreationTime.Before(options.To)
+	}) {
+		start := options.Interval.Truncate(d.CreationTime)
+		if n := len(buckets); n > 0 && buckets[n-1].Start.Equal(start) {
+			buckets[n-1].Count++
+		} else {
+			buckets = append(buckets, Bucket{Start: start, Count: 1})
 		}
 	}
-	return results, nil
+	return FillBuckets(buckets, options), nil
 }
 
-func (m *MockRepository) Store(device *Device) error {
+func (m *MockRepository) Store(ctx context.Context, device *Device) error {
 	if m.Err != nil {
 		return m.Err
 	}
+	m.nextID++
+	device.ID = "mock-" + strconv.Itoa(m.nextID)
+	device.CreationTime = time.Now().UTC().Truncate(time.Microsecond)
+	device.UpdateTime = device.CreationTime
 	m.Devices = append(m.Devices, *device)
 	return nil
 }
 
-func (m *MockRepository) Update(device *Device) error {
+func (m *MockRepository) Update(ctx context.Context, device *Device) error {
 	if m.Err != nil {
 		return m.Err
 	}
+	if device.ID == "" {
+		return ErrMissingID
+	}
+	if device.Name == "" && device.Brand == "" {
+		return ErrInvalidUpdate
+	}
 	for i, d := range m.Devices {
 		if d.ID == device.ID {
-			m.Devices[i] = *device
+			if device.Name != "" {
+				m.Devices[i].Name = device.Name
+			}
+			if device.Brand != "" {
+				m.Devices[i].Brand = device.Brand
+			}
+			m.Devices[i].UpdateTime = time.Now().UTC().Truncate(time.Microsecond)
 			return nil
 		}
 	}
-	return errors.New("device not found")
+	return ErrNotFound
 }
 
-func (m *MockRepository) Remove(id string) error {
+func (m *MockRepository) Remove(ctx context.Context, id string) error {
 	if m.Err != nil {
 		return m.Err
 	}
@@ -71,5 +157,59 @@ func (m *MockRepository) Remove(id string) error {
 			return nil
 		}
 	}
-	return errors.New("device not found")
+	return ErrNotFound
+}
+
+func (m *MockRepository) RemoveByIDs(ctx context.Context, ids []string) ([]Device, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	removed := m.sorted(func(d Device) bool { return wanted[d.ID] })
+
+	kept := m.Devices[:0]
+	for _, d := range m.Devices {
+		if !wanted[d.ID] {
+			kept = append(kept, d)
+		}
+	}
+	m.Devices = kept
+	return removed, nil
+}
+
+// WithTx runs fn against m itself, restoring the devices as they were when
+// fn fails or panics.
+func (m *MockRepository) WithTx(ctx context.Context, options TxOptions, fn func(tx Repository) error) (err error) {
+	if m.Err != nil {
+		return m.Err
+	}
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	devices, nextID := append([]Device(nil), m.Devices...), m.nextID
+	defer func() {
+		if r := recover(); r != nil {
+			m.Devices, m.nextID = devices, nextID
+			panic(r)
+		}
+		if err != nil {
+			m.Devices, m.nextID = devices, nextID
+		}
+	}()
+	return fn(m)
+}
+
+// sorted returns copies of the devices matching keep, ordered like the SQL backends.
+func (m *MockRepository) sorted(keep func(Device) bool) []Device {
+	var results []Device
+	for _, d := range m.Devices {
+		if keep(d) {
+			results = append(results, d)
+		}
+	}
+	SortDevices(results)
+	return results
 }
@@ -0,0 +1,14 @@
+package device_test
+
+import (
+	"testing"
+
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device/devicetest"
+)
+
+func TestMockRepository_Conformance(t *testing.T) {
+	devicetest.RepositoryConformance(t, func(t *testing.T) device.Repository {
+		return &device.MockRepository{}
+	})
+}
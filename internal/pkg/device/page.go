@@ -0,0 +1,100 @@
+package device
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPageToken is returned when a page token was not issued by a previous listing.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// ListOptions filters and paginates a listing. The zero value lists every device.
+type ListOptions struct {
+	// Brand keeps only devices of this brand when set.
+	Brand string
+	// PageSize caps the number of devices returned; 0 returns all of them.
+	PageSize int
+	// PageToken resumes a listing after the last device of a previous page.
+	PageToken string
+	// Fields, as returned by ParseFields, names the fields the caller needs;
+	// backends may leave the others zero. IDs and timestamps are always
+	// loaded, since paging and response validators depend on them.
+	Fields []string
+}
+
+// Page is one page of a listing.
+type Page struct {
+	Devices []Device
+	// NextPageToken fetches the following page; empty on the last one.
+	NextPageToken string
+}
+
+// Cursor is a position in the listing order: creation time, then ID.
+// Pages resume strictly after their cursor, so devices added or removed
+// between requests never shift the remaining pages.
+type Cursor struct {
+	CreationTime time.Time
+	ID           string
+}
+
+// Token encodes the cursor as an opaque page token.
+func (c Cursor) Token() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreationTime.UTC().Format(time.RFC3339Nano) + "|" + c.ID))
+}
+
+// After reports whether d comes after the cursor in the listing order.
+func (c Cursor) After(d Device) bool {
+	if !d.CreationTime.Equal(c.CreationTime) {
+		return d.CreationTime.After(c.CreationTime)
+	}
+	return d.ID > c.ID
+}
+
+// ParseCursor decodes a page token; the empty token yields a nil cursor.
+func ParseCursor(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	at, id, found := strings.Cut(string(raw), "|")
+	if !found || id == "" {
+		return nil, ErrInvalidPageToken
+	}
+	creationTime, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+
+	return &Cursor{CreationTime: creationTime, ID: id}, nil
+}
+
+// NewPage builds a page from devices fetched in listing order with a limit of
+// pageSize+1: the extra device only signals that another page follows.
+func NewPage(devices []Device, pageSize int) Page {
+	if pageSize <= 0 || len(devices) <= pageSize {
+		return Page{Devices: devices}
+	}
+
+	last := devices[pageSize-1]
+	return Page{
+		Devices:       devices[:pageSize],
+		NextPageToken: Cursor{CreationTime: last.CreationTime, ID: last.ID}.Token(),
+	}
+}
+
+// SortDevices orders devices like listings: by creation time, then ID.
+func SortDevices(devices []Device) {
+	sort.Slice(devices, func(i, j int) bool {
+		if !devices[i].CreationTime.Equal(devices[j].CreationTime) {
+			return devices[i].CreationTime.Before(devices[j].CreationTime)
+		}
+		return devices[i].ID < devices[j].ID
+	})
+}
@@ -0,0 +1,180 @@
+package device
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SimilarityThreshold is the trigram similarity above which a word counts as a
+// fuzzy match for a search term, as pg_trgm's default similarity threshold.
+const SimilarityThreshold = 0.3
+
+// brandWeight scales matches in the brand against matches in the name, as
+// PostgreSQL's default ts_rank weights for B and A labels.
+const brandWeight = 0.4
+
+// SearchResult is a device matching a search, with its relevance and the
+// matching words marked up.
+type SearchResult struct {
+	Device     Device     `json:"device"`
+	Score      float64    `json:"score"`
+	Highlights Highlights `json:"highlights"`
+}
+
+// Highlights holds a device's name and brand as HTML-escaped text with the
+// words that matched the search wrapped in <mark> tags.
+type Highlights struct {
+	Name  string `json:"name"`
+	Brand string `json:"brand"`
+}
+
+// SearchTerms splits a search query into lowercase words, dropping
+// punctuation and duplicates.
+func SearchTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), isSeparator) {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// Highlight marks up the words of dvc that match terms.
+func Highlight(dvc Device, terms []string) Highlights {
+	return Highlights{
+		Name:  highlight(dvc.Name, terms),
+		Brand: highlight(dvc.Brand, terms),
+	}
+}
+
+// SearchDevices ranks devices against query for backends without a search
+// index. Each term scores its best match among the words of the name, or of
+// the brand at a lower weight: 1 for a word it is a prefix of, otherwise the
+// trigram similarity when above SimilarityThreshold. A device's score is the
+// mean over terms; devices scoring 0 are left out. Ties keep listing order.
+func SearchDevices(devices []Device, query string, limit int) []SearchResult {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var results []SearchResult
+	for _, dvc := range devices {
+		score := scoreDevice(dvc, terms)
+		if score == 0 {
+			continue
+		}
+		results = append(results, SearchResult{Device: dvc, Score: score, Highlights: Highlight(dvc, terms)})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func scoreDevice(dvc Device, terms []string) float64 {
+	nameWords := strings.FieldsFunc(strings.ToLower(dvc.Name), isSeparator)
+	brandWords := strings.FieldsFunc(strings.ToLower(dvc.Brand), isSeparator)
+
+	var total float64
+	for _, term := range terms {
+		best := 0.0
+		for _, word := range nameWords {
+			best = max(best, matchWord(word, term))
+		}
+		for _, word := range brandWords {
+			best = max(best, brandWeight*matchWord(word, term))
+		}
+		total += best
+	}
+	return total / float64(len(terms))
+}
+
+// matchWord scores how well word matches term: 1 when term is a prefix of
+// word, the trigram similarity when above SimilarityThreshold, 0 otherwise.
+func matchWord(word, term string) float64 {
+	if strings.HasPrefix(word, term) {
+		return 1
+	}
+	if s := similarity(word, term); s >= SimilarityThreshold {
+		return s
+	}
+	return 0
+}
+
+func highlight(text string, terms []string) string {
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := text[start:end]
+		matched := false
+		for _, term := range terms {
+			if matchWord(strings.ToLower(word), term) > 0 {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		start = -1
+	}
+
+	for i, r := range text {
+		if !isSeparator(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+		b.WriteString(html.EscapeString(string(r)))
+	}
+	flush(len(text))
+	return b.String()
+}
+
+// similarity is the pg_trgm similarity of two lowercase words: the share of
+// their trigrams, padded with two leading and one trailing space, they have
+// in common.
+func similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	common := 0
+	for t := range ta {
+		if tb[t] {
+			common++
+		}
+	}
+	union := len(ta) + len(tb) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+func trigrams(word string) map[string]bool {
+	runes := []rune("  " + word + " ")
+	set := make(map[string]bool, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = true
+	}
+	return set
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
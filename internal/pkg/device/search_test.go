@@ -0,0 +1,37 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"galaxy", "s23", "ultra"}, SearchTerms("  Galaxy S23-Ultra, galaxy!"))
+	assert.Empty(t, SearchTerms("?!"))
+}
+
+func TestHighlight(t *testing.T) {
+	h := Highlight(Device{Name: "Galaxy S23 <Ultra>", Brand: "Samsung"}, []string{"galxy", "s2"})
+	assert.Equal(t, "<mark>Galaxy</mark> <mark>S23</mark> &lt;Ultra&gt;", h.Name, "fuzzy and prefix matches, escaped")
+	assert.Equal(t, "Samsung", h.Brand)
+}
+
+func TestSearchDevices_RanksNamesAboveBrands(t *testing.T) {
+	devices := []Device{
+		{ID: "1", Name: "Phone", Brand: "Pixel"},
+		{ID: "2", Name: "Pixel 8", Brand: "Google"},
+	}
+
+	results := SearchDevices(devices, "pixel", 0)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "2", results[0].Device.ID)
+		assert.Greater(t, results[0].Score, results[1].Score)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, similarity("galaxy", "galaxy"))
+	assert.InDelta(t, 4.0/9, similarity("galaxy", "galxy"), 1e-9)
+	assert.Zero(t, similarity("galaxy", "pixel"))
+}
@@ -0,0 +1,86 @@
+package device
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidInterval is returned when a histogram interval is not day, week or month.
+var ErrInvalidInterval = errors.New("invalid interval, expected day, week or month")
+
+// Interval is the width of a histogram bucket. Buckets start at midnight UTC;
+// weeks start on Monday and months on their first day.
+type Interval string
+
+const (
+	Day   Interval = "day"
+	Week  Interval = "week"
+	Month Interval = "month"
+)
+
+// ParseInterval validates an interval name.
+func ParseInterval(s string) (Interval, error) {
+	switch i := Interval(s); i {
+	case Day, Week, Month:
+		return i, nil
+	}
+	return "", ErrInvalidInterval
+}
+
+// Truncate returns the start of the bucket containing t.
+func (i Interval) Truncate(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	switch i {
+	case Week:
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case Month:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Add moves t by n intervals, backwards when n is negative.
+func (i Interval) Add(t time.Time, n int) time.Time {
+	switch i {
+	case Week:
+		return t.AddDate(0, 0, 7*n)
+	case Month:
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+// HistogramOptions selects the devices counted by a histogram, those created
+// in [From, To), and the width of its buckets.
+type HistogramOptions struct {
+	Interval Interval
+	From     time.Time
+	To       time.Time
+}
+
+// Bucket counts the devices created in the interval starting at Start.
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+// FillBuckets returns a bucket for every interval from the one containing
+// options.From up to options.To, taking counts from the non-empty, ordered
+// buckets given and zero for the rest.
+func FillBuckets(buckets []Bucket, options HistogramOptions) []Bucket {
+	var filled []Bucket
+	for start := options.Interval.Truncate(options.From); start.Before(options.To); start = options.Interval.Add(start, 1) {
+		bucket := Bucket{Start: start}
+		for len(buckets) > 0 && !buckets[0].Start.After(start) {
+			if buckets[0].Start.Equal(start) {
+				bucket.Count += buckets[0].Count
+			}
+			buckets = buckets[1:]
+		}
+		filled = append(filled, bucket)
+	}
+	return filled
+}
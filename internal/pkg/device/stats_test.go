@@ -0,0 +1,41 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterval_Truncate(t *testing.T) {
+	// A Sunday evening west of UTC, already Monday in UTC.
+	at := time.Date(2024, 3, 10, 22, 30, 0, 0, time.FixedZone("EST", -5*3600))
+
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), Day.Truncate(at))
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), Week.Truncate(at))
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Week.Truncate(at.Add(-4*time.Hour)))
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Month.Truncate(at))
+}
+
+func TestFillBuckets(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	options := HistogramOptions{Interval: Day, From: day(1).Add(12 * time.Hour), To: day(4).Add(time.Hour)}
+
+	filled := FillBuckets([]Bucket{{Start: day(2), Count: 3}, {Start: day(4), Count: 1}}, options)
+
+	assert.Equal(t, []Bucket{
+		{Start: day(1)},
+		{Start: day(2), Count: 3},
+		{Start: day(3)},
+		{Start: day(4), Count: 1},
+	}, filled)
+}
+
+func TestParseInterval(t *testing.T) {
+	interval, err := ParseInterval("week")
+	assert.NoError(t, err)
+	assert.Equal(t, Week, interval)
+
+	_, err = ParseInterval("year")
+	assert.ErrorIs(t, err, ErrInvalidInterval)
+}
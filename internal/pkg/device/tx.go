@@ -0,0 +1,53 @@
+package device
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrTxUnsupported is returned by WithTx when the wrapped repository cannot run transactions.
+	ErrTxUnsupported = errors.New("repository does not support transactions")
+	// ErrInvalidIsolation is returned by WithTx when TxOptions name an unknown isolation level.
+	ErrInvalidIsolation = errors.New("invalid isolation level, expected read committed, repeatable read or serializable")
+)
+
+// Isolation is a transaction isolation level, named as in SQL.
+type Isolation string
+
+const (
+	ReadCommitted  Isolation = "read committed"
+	RepeatableRead Isolation = "repeatable read"
+	Serializable   Isolation = "serializable"
+)
+
+// TxOptions configures a transaction. The zero value uses the backend's
+// default isolation level. SQLite runs every transaction serializable,
+// whatever level is asked for.
+type TxOptions struct {
+	Isolation Isolation
+}
+
+// Validate returns ErrInvalidIsolation unless the isolation level is one of
+// the constants above or empty.
+func (o TxOptions) Validate() error {
+	switch o.Isolation {
+	case "", ReadCommitted, RepeatableRead, Serializable:
+		return nil
+	}
+	return ErrInvalidIsolation
+}
+
+// Transactor is implemented by repositories that can run several operations
+// as one unit of work.
+//
+// WithTx calls fn with a repository whose operations all belong to one
+// transaction, committing it when fn returns nil and rolling it back when fn
+// returns an error or panics. Backends retry transactions that fail on a
+// serialization conflict, so fn may run more than once and must not have
+// effects outside tx. Calling WithTx on tx joins the running transaction.
+// Options naming an unknown isolation level fail with ErrInvalidIsolation
+// before anything runs.
+type Transactor interface {
+	WithTx(ctx context.Context, options TxOptions, fn func(tx Repository) error) error
+}
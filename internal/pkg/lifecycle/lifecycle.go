@@ -0,0 +1,144 @@
+// Package lifecycle starts the long-running parts of the service and shuts
+// them down in order.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// Manager owns components and stops them in reverse order of registration:
+// register dependencies (the repository) before their users (the HTTP
+// server), so users are drained first.
+type Manager struct {
+	logger     *zap.Logger
+	timeout    time.Duration
+	components []*component
+}
+
+type component struct {
+	name string
+	// run blocks while the component works; nil for resources that only need closing.
+	run  func() error
+	stop func(ctx context.Context) error
+	done chan struct{}
+	err  error
+}
+
+// New returns a Manager that allows shutdown up to timeout to stop every component.
+func New(logger *zap.Logger, timeout time.Duration) *Manager {
+	return &Manager{logger: logger, timeout: timeout}
+}
+
+// Add registers a component. run blocks while the component serves and must
+// return once stop has been called; a run returning an error before shutdown
+// shuts the whole service down. run may be nil for resources that are only
+// closed, and stop may be nil for components that need no stopping.
+func (m *Manager) Add(name string, run func() error, stop func(ctx context.Context) error) {
+	m.components = append(m.components, &component{name: name, run: run, stop: stop})
+}
+
+// Go registers a background worker. run is called with a context that is
+// canceled at shutdown and must return soon after; context.Canceled is not
+// reported as an error. The context carries a logger naming the worker.
+func (m *Manager) Go(name string, run func(ctx context.Context) error) {
+	ctx, cancel := context.WithCancel(logging.WithLogger(context.Background(), m.logger.With(zap.String("component", name))))
+	m.Add(name, func() error {
+		if err := run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	}, func(context.Context) error {
+		cancel()
+		return nil
+	})
+}
+
+// Run starts every component and blocks until ctx is canceled or a component
+// fails, then stops the components in reverse order of registration, waiting
+// for each to finish before stopping the next, within the shutdown timeout.
+// It returns the failure that triggered the shutdown, if any, joined with
+// every error raised while stopping.
+func (m *Manager) Run(ctx context.Context) error {
+	failed := make(chan *component, len(m.components))
+	for _, c := range m.components {
+		c.done = make(chan struct{})
+		if c.run == nil {
+			close(c.done)
+			continue
+		}
+
+		go func(c *component) {
+			defer close(c.done)
+			if c.err = c.run(); c.err != nil {
+				failed <- c
+			}
+		}(c)
+	}
+
+	var errs []error
+	var trigger *component
+	select {
+	case <-ctx.Done():
+		m.logger.Info("shutting down")
+	case trigger = <-failed:
+		m.logger.With(zap.String("component", trigger.name), zap.Error(trigger.err)).Error("component failed, shutting down")
+		errs = append(errs, fmt.Errorf("%s: %w", trigger.name, trigger.err))
+	}
+
+	if err := m.shutdown(trigger); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Shutdown stops every component without running any, in reverse order of
+// registration, for when the service fails to start after registering some:
+// the resources registered so far are still released. It is an alternative
+// to Run, not to be called after it.
+func (m *Manager) Shutdown() error {
+	for _, c := range m.components {
+		c.done = make(chan struct{})
+		close(c.done)
+	}
+	return m.shutdown(nil)
+}
+
+// shutdown stops the components; trigger, whose failure started the
+// shutdown, has had its error reported already.
+func (m *Manager) shutdown(trigger *component) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(m.components) - 1; i >= 0; i-- {
+		c := m.components[i]
+		start := time.Now()
+
+		if c.stop != nil {
+			if err := c.stop(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("stopping %s: %w", c.name, err))
+			}
+		}
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("stopping %s: %w", c.name, ctx.Err()))
+			continue
+		}
+		if c.err != nil && c != trigger {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, c.err))
+		}
+
+		m.logger.With(zap.String("component", c.name), zap.Duration("took", time.Since(start))).Info("component stopped")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,140 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// recorder collects the order in which components are stopped.
+type recorder struct {
+	mu      sync.Mutex
+	stopped []string
+}
+
+func (r *recorder) record(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = append(r.stopped, name)
+}
+
+// blocking returns a run/stop pair that serves until stopped.
+func (r *recorder) blocking(name string) (func() error, func(context.Context) error) {
+	stop := make(chan struct{})
+	return func() error {
+			<-stop
+			r.record(name)
+			return nil
+		}, func(context.Context) error {
+			close(stop)
+			return nil
+		}
+}
+
+func TestManager_StopsInReverseOrder(t *testing.T) {
+	r := &recorder{}
+	m := New(zap.NewNop(), time.Second)
+
+	m.Add("repository", nil, func(context.Context) error {
+		r.record("repository")
+		return nil
+	})
+	m.Go("worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		r.record("worker")
+		return ctx.Err()
+	})
+	run, stop := r.blocking("http")
+	m.Add("http", run, stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NoError(t, m.Run(ctx))
+	assert.Equal(t, []string{"http", "worker", "repository"}, r.stopped)
+}
+
+func TestManager_FailureShutsDown(t *testing.T) {
+	r := &recorder{}
+	m := New(zap.NewNop(), time.Second)
+
+	run, stop := r.blocking("worker")
+	m.Add("worker", run, stop)
+	m.Add("http", func() error {
+		return errors.New("listen tcp :8080: address already in use")
+	}, nil)
+
+	err := m.Run(context.Background())
+
+	assert.EqualError(t, err, "http: listen tcp :8080: address already in use")
+	assert.Equal(t, []string{"worker"}, r.stopped)
+}
+
+func TestManager_ReportsStopErrors(t *testing.T) {
+	m := New(zap.NewNop(), time.Second)
+
+	m.Add("tracing", nil, func(context.Context) error {
+		return errors.New("exporter unreachable")
+	})
+	m.Go("sweeper", func(ctx context.Context) error {
+		<-ctx.Done()
+		return errors.New("sweep interrupted")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.EqualError(t, m.Run(ctx), "sweeper: sweep interrupted\nstopping tracing: exporter unreachable")
+}
+
+func TestManager_DrainTimeout(t *testing.T) {
+	r := &recorder{}
+	m := New(zap.NewNop(), 20*time.Millisecond)
+
+	m.Add("repository", nil, func(context.Context) error {
+		r.record("repository")
+		return nil
+	})
+	m.Add("http", func() error {
+		select {}
+	}, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := m.Run(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []string{"repository"}, r.stopped, "later components are still closed after the deadline")
+}
+
+func TestManager_ShutdownWithoutRun(t *testing.T) {
+	r := &recorder{}
+	m := New(zap.NewNop(), time.Second)
+
+	m.Add("repository", nil, func(context.Context) error {
+		r.record("repository")
+		return nil
+	})
+	m.Go("sweeper", func(ctx context.Context) error {
+		r.record("sweeper ran")
+		return nil
+	})
+	m.Add("tracer", nil, func(context.Context) error {
+		r.record("tracer")
+		return errors.New("flush failed")
+	})
+
+	err := m.Shutdown()
+
+	assert.EqualError(t, err, "stopping tracer: flush failed")
+	assert.Equal(t, []string{"tracer", "repository"}, r.stopped)
+}
@@ -0,0 +1,18 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestFromContext(t *testing.T) {
+	fallback := zap.NewNop()
+	assert.Same(t, fallback, FromContext(context.Background(), fallback))
+
+	logger := zap.NewExample()
+	ctx := WithLogger(context.Background(), logger)
+	assert.Same(t, logger, FromContext(ctx, fallback))
+}
@@ -0,0 +1,14 @@
+package ratelimit_test
+
+import (
+	"testing"
+
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit/ratelimittest"
+)
+
+func TestMemoryStore_Conformance(t *testing.T) {
+	ratelimittest.StoreConformance(t, func(t *testing.T) ratelimit.Store {
+		return ratelimit.NewMemoryStore()
+	})
+}
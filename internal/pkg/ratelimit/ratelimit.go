@@ -0,0 +1,130 @@
+// Package ratelimit implements per-key request quotas with the generic cell
+// rate algorithm (GCRA), a token bucket that stores a single timestamp per key.
+//
+// A limit of N requests per period P spaces requests by an emission interval
+// T = P/N. Each key has a theoretical arrival time (TAT): the moment its
+// bucket would be full again. A request at now is allowed if advancing the
+// TAT to max(TAT, now)+T keeps it within now+P, so a client may burst up to N
+// requests and then continue at one per T.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limit is a quota of Requests per Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// interval returns the emission interval, the time one request occupies.
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// Store keeps the theoretical arrival time of each key. Implementations must
+// make Reserve atomic per key.
+type Store interface {
+	// Reserve advances the TAT of key to max(TAT, now)+interval if the result
+	// does not exceed now+period. It returns the TAT after the call, at least
+	// now, and whether it was advanced.
+	Reserve(ctx context.Context, key string, now time.Time, interval, period time.Duration) (time.Time, bool, error)
+}
+
+// Result describes the state of a key's quota after a request.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is the number of requests that would be allowed right now.
+	Remaining int
+	// Reset is how long until the quota is fully replenished.
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed; zero when Allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter applies limits to keys, keeping state in a Store.
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+// New returns a Limiter backed by store.
+func New(store Store) *Limiter {
+	return &Limiter{store: store, now: time.Now}
+}
+
+// Allow consumes one request of key's quota under limit.
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := l.now()
+	interval := limit.interval()
+
+	tat, allowed, err := l.store.Reserve(ctx, key, now, interval, limit.Period)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		Allowed: allowed,
+		Limit:   limit.Requests,
+		Reset:   tat.Sub(now),
+	}
+	if allowed {
+		result.Remaining = int((limit.Period - result.Reset) / interval)
+	} else {
+		result.RetryAfter = result.Reset + interval - limit.Period
+	}
+
+	return result, nil
+}
+
+// MemoryStore keeps TATs in process memory; limits are per instance.
+type MemoryStore struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tats: make(map[string]time.Time)}
+}
+
+// sweepEvery bounds how often Reserve drops keys whose quota is full again.
+const sweepEvery = time.Minute
+
+// Reserve implements Store.
+func (s *MemoryStore) Reserve(_ context.Context, key string, now time.Time, interval, period time.Duration) (time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepEvery {
+		s.sweep(now)
+	}
+
+	tat := s.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+
+	next := tat.Add(interval)
+	if next.After(now.Add(period)) {
+		return tat, false, nil
+	}
+
+	s.tats[key] = next
+	return next, true, nil
+}
+
+// sweep drops keys whose TAT has passed, which are equivalent to absent ones.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, tat := range s.tats {
+		if !tat.After(now) {
+			delete(s.tats, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_SweepsReplenishedKeys(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+
+	_, _, err := store.Reserve(ctx, "a", now, time.Second, time.Minute)
+	require.NoError(t, err)
+	_, _, err = store.Reserve(ctx, "b", now.Add(time.Second), time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	_, _, err = store.Reserve(ctx, "c", now.Add(2*time.Minute), time.Second, time.Minute)
+	require.NoError(t, err)
+
+	assert.NotContains(t, store.tats, "a")
+	assert.Contains(t, store.tats, "b")
+	assert.Contains(t, store.tats, "c")
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter := New(NewMemoryStore())
+	limiter.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Period: time.Minute}
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := limiter.Allow(ctx, "client", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, remaining, result.Remaining)
+		assert.Zero(t, result.RetryAfter)
+	}
+
+	result, err := limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: false, Limit: 3, Remaining: 0, Reset: time.Minute, RetryAfter: 20 * time.Second}, result)
+
+	now = now.Add(30 * time.Second)
+	result, err = limiter.Allow(ctx, "client", limit)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 50 * time.Second}, result)
+}
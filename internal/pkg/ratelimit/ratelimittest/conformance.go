@@ -0,0 +1,72 @@
+// Package ratelimittest holds the behavior every ratelimit.Store
+// implementation must satisfy, for their tests to run.
+package ratelimittest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit"
+)
+
+// StoreConformance runs the behavior every ratelimit.Store implementation must satisfy.
+// newStore is called once per subtest and must return an empty store.
+func StoreConformance(t *testing.T, newStore func(t *testing.T) ratelimit.Store) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("ReserveUpToPeriod", func(t *testing.T) {
+		store := newStore(t)
+
+		for i := 1; i <= 3; i++ {
+			tat, ok, err := store.Reserve(ctx, "client", now, 20*time.Second, time.Minute)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.True(t, tat.Equal(now.Add(time.Duration(i)*20*time.Second)), "tat %s", tat)
+		}
+
+		tat, ok, err := store.Reserve(ctx, "client", now, 20*time.Second, time.Minute)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.True(t, tat.Equal(now.Add(time.Minute)), "denied reservations leave the tat unchanged")
+	})
+
+	t.Run("ReplenishesOverTime", func(t *testing.T) {
+		store := newStore(t)
+
+		for i := 0; i < 3; i++ {
+			_, _, err := store.Reserve(ctx, "client", now, 20*time.Second, time.Minute)
+			require.NoError(t, err)
+		}
+
+		tat, ok, err := store.Reserve(ctx, "client", now.Add(20*time.Second), 20*time.Second, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, tat.Equal(now.Add(80*time.Second)), "tat %s", tat)
+
+		later := now.Add(time.Hour)
+		tat, ok, err = store.Reserve(ctx, "client", later, 20*time.Second, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, tat.Equal(later.Add(20*time.Second)), "an idle key starts from now, tat %s", tat)
+	})
+
+	t.Run("KeysAreIndependent", func(t *testing.T) {
+		store := newStore(t)
+
+		_, ok, err := store.Reserve(ctx, "a", now, time.Minute, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		_, ok, err = store.Reserve(ctx, "a", now, time.Minute, time.Minute)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		_, ok, err = store.Reserve(ctx, "b", now, time.Minute, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
@@ -2,11 +2,14 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx"
+	pgxv5 "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
 )
@@ -14,33 +17,180 @@ import (
 // Client connects to a database and implements a repository interface.
 type Client struct {
 	db *pgxpool.Pool
+	// conn runs the queries: the pool, or the transaction of a client
+	// handed to a WithTx callback.
+	conn querier
+	tx   bool
 }
 
-func New(connString string) (*Client, error) {
+// PoolOptions tunes the connection pool; zero values keep the pgx defaults.
+type PoolOptions struct {
+	MaxConns int32
+	MinConns int32
+}
+
+// New connects to the PostgreSQL database at connString and applies migrations.
+func New(connString string, options PoolOptions) (*Client, error) {
 	ctx := context.Background()
 
-	db, err := pgxpool.New(ctx, connString)
+	config, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, err
 	}
+	config.ConnConfig.Tracer = queryTracer{}
+	if options.MaxConns > 0 {
+		config.MaxConns = options.MaxConns
+	}
+	if options.MinConns > 0 {
+		config.MinConns = options.MinConns
+	}
 
-	createSchema := `
-	CREATE TABLE IF NOT EXISTS devices (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		brand TEXT NOT NULL,
-		creation_time TIMESTAMPTZ NOT NULL,
-		update_time TIMESTAMPTZ NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_brand ON devices(brand);
-	`
-	_, err = db.Exec(ctx, createSchema)
+	db, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
+		return nil, err
+	}
+
+	if err := migratePostgres(ctx, db); err != nil {
 		db.Close()
 		return nil, err
 	}
 
-	return &Client{db: db}, nil
+	return &Client{db: db, conn: db}, nil
+}
+
+// now returns the current time at the precision PostgreSQL stores.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// updateStatement builds the partial UPDATE for the non-empty fields of dvc,
+// binding updateTime in the encoding the backend stores.
+func updateStatement(dvc *device.Device, updateTime any) (string, []any, error) {
+	if dvc.ID == "" {
+		return "", nil, device.ErrMissingID
+	}
+
+	if dvc.Name != "" && dvc.Brand != "" {
+		return "UPDATE devices SET name=$2, brand=$3, update_time=$4 WHERE id=$1", []any{dvc.ID, dvc.Name, dvc.Brand, updateTime}, nil
+	} else if dvc.Name != "" {
+		return "UPDATE devices SET name=$2, update_time=$3 WHERE id=$1", []any{dvc.ID, dvc.Name, updateTime}, nil
+	} else if dvc.Brand != "" {
+		return "UPDATE devices SET brand=$2, update_time=$3 WHERE id=$1", []any{dvc.ID, dvc.Brand, updateTime}, nil
+	}
+
+	return "", nil, device.ErrInvalidUpdate
+}
+
+// listPageStatement builds the SELECT for ListPage, binding the cursor time
+// in the encoding the backend stores. It fetches one device more than the page
+// size so device.NewPage can tell whether another page follows. Columns left
+// out of options.Fields are selected as empty strings, so rows scan the same.
+func listPageStatement(options device.ListOptions, encodeTime func(time.Time) any) (string, []any, error) {
+	cursor, err := device.ParseCursor(options.PageToken)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		conditions []string
+		args       []any
+	)
+	if options.Brand != "" {
+		args = append(args, options.Brand)
+		conditions = append(conditions, fmt.Sprintf("brand=$%d", len(args)))
+	}
+	if cursor != nil {
+		args = append(args, encodeTime(cursor.CreationTime), cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(creation_time, id) > ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	column := func(field, name string) string {
+		if device.Selects(options.Fields, field) {
+			return name
+		}
+		return "'' AS " + name
+	}
+
+	query := fmt.Sprintf("SELECT id, %s, %s, creation_time, update_time FROM devices", column("name", "name"), column("brand", "brand"))
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY creation_time, id"
+	if options.PageSize > 0 {
+		args = append(args, options.PageSize+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	return query, args, nil
+}
+
+// requireAffected maps a statement that touched no rows to device.ErrNotFound.
+func requireAffected(rows int64) error {
+	if rows == 0 {
+		return device.ErrNotFound
+	}
+	return nil
+}
+
+// searchStatement ranks full-text matches of every term as a prefix, names
+// above brands.
+const searchStatement = `SELECT id, name, brand, creation_time, update_time, ts_rank(search, q)
+	FROM devices, to_tsquery('simple', $1) q
+	WHERE search @@ q
+	ORDER BY 6 DESC, creation_time, id`
+
+// fuzzySearchStatement ranks devices by the trigram similarity of the query to
+// their closest words, for queries with misspelled terms. It runs after
+// lowering pg_trgm.word_similarity_threshold to device.SimilarityThreshold.
+const fuzzySearchStatement = `SELECT id, name, brand, creation_time, update_time, word_similarity($1, name || ' ' || brand)
+	FROM devices
+	WHERE $1 <% (name || ' ' || brand)
+	ORDER BY 6 DESC, creation_time, id`
+
+// migrationLockID is the advisory lock key serialising migrations across
+// replicas.
+const migrationLockID = 0x64657669636573 // "devices"
+
+// migratePostgres applies the migrations on one connection holding the
+// migration advisory lock, so replicas starting together take turns and the
+// later ones find the schema up to date.
+func migratePostgres(ctx context.Context, db *pgxpool.Pool) error {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return err
+	}
+	defer func() {
+		// The lock belongs to the session, not the checkout: close the
+		// connection rather than return it to the pool still locked.
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			_ = conn.Conn().Close(context.Background())
+		}
+	}()
+
+	return migrate(ctx, pgMigrator{conn})
+}
+
+// pgMigrator runs migrations on a pgx connection or pool.
+type pgMigrator struct {
+	db querier
+}
+
+func (m pgMigrator) exec(ctx context.Context, query string, args ...any) error {
+	_, err := m.db.Exec(ctx, query, args...)
+	return err
+}
+
+func (m pgMigrator) postgres() bool { return true }
+
+func (m pgMigrator) version(ctx context.Context) (int, error) {
+	var v int
+	err := m.db.QueryRow(ctx, selectSchemaVersion).Scan(&v)
+	return v, err
 }
 
 // Close closes all database pool connections.
@@ -48,14 +198,24 @@ func (c *Client) Close() {
 	c.db.Close()
 }
 
+// Ping checks that the database is reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.db.Ping(ctx)
+}
+
+// MigrationStatus returns the applied schema version and the latest one known.
+func (c *Client) MigrationStatus(ctx context.Context) (current int, latest int, err error) {
+	return migrationStatus(ctx, pgMigrator{c.db})
+}
+
 // Store adds a new device.
-func (c *Client) Store(device *device.Device) error {
+func (c *Client) Store(ctx context.Context, device *device.Device) error {
 	device.ID = uuid.New().String()
-	device.CreationTime = time.Now()
+	device.CreationTime = now()
 	device.UpdateTime = device.CreationTime
 
-	_, err := c.db.Exec(
-		context.Background(),
+	_, err := c.conn.Exec(
+		ctx,
 		"INSERT INTO devices (id, name, brand, creation_time, update_time) VALUES ($1, $2, $3, $4, $5)",
 		device.ID, device.Name, device.Brand, device.CreationTime, device.UpdateTime,
 	)
@@ -64,8 +224,8 @@ func (c *Client) Store(device *device.Device) error {
 }
 
 // FindByID gets a device by its ID.
-func (c *Client) FindByID(id string) (*device.Device, error) {
-	row := c.db.QueryRow(context.Background(), "SELECT id, name, brand, creation_time, update_time FROM devices WHERE id=$1", id)
+func (c *Client) FindByID(ctx context.Context, id string) (*device.Device, error) {
+	row := c.conn.QueryRow(ctx, "SELECT id, name, brand, creation_time, update_time FROM devices WHERE id=$1", id)
 
 	device := &device.Device{}
 
@@ -79,9 +239,33 @@ func (c *Client) FindByID(id string) (*device.Device, error) {
 	return device, nil
 }
 
+// FindByIDs gets the devices with the given IDs in a single query.
+func (c *Client) FindByIDs(ctx context.Context, ids []string) ([]device.Device, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	rows, err := c.conn.Query(ctx, "SELECT id, name, brand, creation_time, update_time FROM devices WHERE id = ANY($1) ORDER BY creation_time, id", ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []device.Device
+	for rows.Next() {
+		var dvc device.Device
+		if err := rows.Scan(&dvc.ID, &dvc.Name, &dvc.Brand, &dvc.CreationTime, &dvc.UpdateTime); err != nil {
+			return nil, err
+		}
+		devices = append(devices, dvc)
+	}
+
+	return devices, rows.Err()
+}
+
 // List gets all devices.
-func (c *Client) List() ([]device.Device, error) {
-	rows, err := c.db.Query(context.Background(), "SELECT id, name, brand, creation_time, update_time FROM devices")
+func (c *Client) List(ctx context.Context) ([]device.Device, error) {
+	rows, err := c.conn.Query(ctx, "SELECT id, name, brand, creation_time, update_time FROM devices ORDER BY creation_time, id")
 	if err != nil {
 		return nil, err
 	}
@@ -99,47 +283,89 @@ func (c *Client) List() ([]device.Device, error) {
 	return devices, nil
 }
 
-// Update updates a device.
-func (c *Client) Update(device *device.Device) error {
-	if device.ID == "" {
-		return errors.New("device id is required")
+// ListPage gets one page of devices matching options.
+func (c *Client) ListPage(ctx context.Context, options device.ListOptions) (device.Page, error) {
+	query, args, err := listPageStatement(options, func(t time.Time) any { return t })
+	if err != nil {
+		return device.Page{}, err
 	}
 
-	if device.Name != "" && device.Brand != "" {
-		_, err := c.db.Exec(
-			context.Background(),
-			"UPDATE devices SET name=$2, brand=$3, update_time=$4 WHERE id=$1",
-			device.ID, device.Name, device.Brand, time.Now(),
-		)
-		return err
-	} else if device.Name != "" {
-		_, err := c.db.Exec(
-			context.Background(),
-			"UPDATE devices SET name=$2, update_time=$3 WHERE id=$1",
-			device.ID, device.Name, time.Now(),
-		)
+	rows, err := c.conn.Query(ctx, query, args...)
+	if err != nil {
+		return device.Page{}, err
+	}
+	defer rows.Close()
+
+	var devices []device.Device
+	for rows.Next() {
+		var dvc device.Device
+		if err := rows.Scan(&dvc.ID, &dvc.Name, &dvc.Brand, &dvc.CreationTime, &dvc.UpdateTime); err != nil {
+			return device.Page{}, err
+		}
+		devices = append(devices, dvc)
+	}
+	if err := rows.Err(); err != nil {
+		return device.Page{}, err
+	}
+
+	return device.NewPage(devices, options.PageSize), nil
+}
+
+// Update updates a device.
+func (c *Client) Update(ctx context.Context, device *device.Device) error {
+	query, args, err := updateStatement(device, now())
+	if err != nil {
 		return err
-	} else if device.Brand != "" {
-		_, err := c.db.Exec(
-			context.Background(),
-			"UPDATE devices SET brand=$2, update_time=$3 WHERE id=$1",
-			device.ID, device.Brand, time.Now(),
-		)
+	}
+
+	tag, err := c.conn.Exec(ctx, query, args...)
+	if err != nil {
 		return err
 	}
 
-	return errors.New("invalid update request")
+	return requireAffected(tag.RowsAffected())
 }
 
 // Remove deletes a device by its ID.
-func (c *Client) Remove(id string) error {
-	_, err := c.db.Exec(context.Background(), "DELETE FROM devices WHERE id=$1", id)
-	return err
+func (c *Client) Remove(ctx context.Context, id string) error {
+	tag, err := c.conn.Exec(ctx, "DELETE FROM devices WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(tag.RowsAffected())
+}
+
+// RemoveByIDs deletes the devices with the given IDs in a single statement.
+func (c *Client) RemoveByIDs(ctx context.Context, ids []string) ([]device.Device, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	rows, err := c.conn.Query(ctx, `WITH removed AS (
+		DELETE FROM devices WHERE id = ANY($1) RETURNING id, name, brand, creation_time, update_time
+	)
+	SELECT id, name, brand, creation_time, update_time FROM removed ORDER BY creation_time, id`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []device.Device
+	for rows.Next() {
+		var dvc device.Device
+		if err := rows.Scan(&dvc.ID, &dvc.Name, &dvc.Brand, &dvc.CreationTime, &dvc.UpdateTime); err != nil {
+			return nil, err
+		}
+		devices = append(devices, dvc)
+	}
+
+	return devices, rows.Err()
 }
 
 // FindByBrand gets a list of devices by brand.
-func (c *Client) FindByBrand(brand string) ([]device.Device, error) {
-	rows, err := c.db.Query(context.Background(), "SELECT id, name, brand, creation_time, update_time FROM devices WHERE brand=$1", brand)
+func (c *Client) FindByBrand(ctx context.Context, brand string) ([]device.Device, error) {
+	rows, err := c.conn.Query(ctx, "SELECT id, name, brand, creation_time, update_time FROM devices WHERE brand=$1 ORDER BY creation_time, id", brand)
 	if err != nil {
 		return nil, err
 	}
@@ -156,3 +382,133 @@ func (c *Client) FindByBrand(brand string) ([]device.Device, error) {
 
 	return devices, nil
 }
+
+// Search finds devices by full-text search over their names and brands,
+// falling back to trigram similarity when no device contains every term.
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]device.SearchResult, error) {
+	terms := device.SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	results, err := search(ctx, c.conn, terms, searchStatement, strings.Join(prefixes, " & "), limit)
+	if err != nil || len(results) > 0 {
+		return results, err
+	}
+
+	tx, err := c.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL pg_trgm.word_similarity_threshold = %g", device.SimilarityThreshold)); err != nil {
+		return nil, err
+	}
+	results, err = search(ctx, tx, terms, fuzzySearchStatement, strings.Join(terms, " "), limit)
+	if err != nil {
+		return nil, err
+	}
+	return results, tx.Commit(ctx)
+}
+
+// querier runs queries on the pool or inside a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgxv5.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgxv5.Row
+	Begin(ctx context.Context) (pgxv5.Tx, error)
+}
+
+// search runs one of the search statements, which select a device and its score.
+func search(ctx context.Context, db querier, terms []string, query, arg string, limit int) ([]device.SearchResult, error) {
+	args := []any{arg}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []device.SearchResult
+	for rows.Next() {
+		var r device.SearchResult
+		if err := rows.Scan(&r.Device.ID, &r.Device.Name, &r.Device.Brand, &r.Device.CreationTime, &r.Device.UpdateTime, &r.Score); err != nil {
+			return nil, err
+		}
+		r.Highlights = device.Highlight(r.Device, terms)
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
+
+// CreationHistogram counts the devices created per interval, grouping by the
+// start of their interval in UTC; date_trunc starts weeks on Monday.
+func (c *Client) CreationHistogram(ctx context.Context, options device.HistogramOptions) ([]device.Bucket, error) {
+	if _, err := device.ParseInterval(string(options.Interval)); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.conn.Query(ctx, `SELECT date_trunc($1, creation_time AT TIME ZONE 'UTC'), COUNT(*) FROM devices
+		WHERE creation_time >= $2 AND creation_time < $3
+		GROUP BY 1 ORDER BY 1`,
+		string(options.Interval), options.From, options.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []device.Bucket
+	for rows.Next() {
+		var b device.Bucket
+		if err := rows.Scan(&b.Start, &b.Count); err != nil {
+			return nil, err
+		}
+		b.Start = b.Start.UTC()
+		buckets = append(buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return device.FillBuckets(buckets, options), nil
+}
+
+// CountByBrand gets the number of devices per brand, of the given brands only
+// when any are passed.
+func (c *Client) CountByBrand(ctx context.Context, brands ...string) (map[string]int, error) {
+	query, args := "SELECT brand, COUNT(*) FROM devices GROUP BY brand", []any(nil)
+	if len(brands) > 0 {
+		query, args = "SELECT brand, COUNT(*) FROM devices WHERE brand = ANY($1) GROUP BY brand", []any{brands}
+	}
+
+	rows, err := c.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			brand string
+			count int
+		)
+		if err := rows.Scan(&brand, &count); err != nil {
+			return nil, err
+		}
+		counts[brand] = count
+	}
+
+	return counts, rows.Err()
+}
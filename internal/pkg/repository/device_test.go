@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device/devicetest"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit/ratelimittest"
+)
+
+// newPostgresClient connects to the database in POSTGRES_TEST_CONN, skipping the test when unset.
+// The devices table is emptied, so never point it at a database holding real data.
+func newPostgresClient(t *testing.T) *Client {
+	t.Helper()
+	connString, found := os.LookupEnv("POSTGRES_TEST_CONN")
+	if !found {
+		t.Skip("POSTGRES_TEST_CONN not set")
+	}
+
+	client, err := New(connString, PoolOptions{})
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+
+	_, err = client.db.Exec(context.Background(), "TRUNCATE devices")
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestClient_Conformance(t *testing.T) {
+	devicetest.RepositoryConformance(t, func(t *testing.T) device.Repository {
+		return newPostgresClient(t)
+	})
+}
+
+func TestRateLimitStore_Conformance(t *testing.T) {
+	ratelimittest.StoreConformance(t, func(t *testing.T) ratelimit.Store {
+		client := newPostgresClient(t)
+		_, err := client.db.Exec(context.Background(), "TRUNCATE rate_limits")
+		require.NoError(t, err)
+		return client.RateLimitStore()
+	})
+}
+
+func TestClient_MigrationsTakeTurns(t *testing.T) {
+	client := newPostgresClient(t)
+	ctx := context.Background()
+
+	errs := make(chan error, 3)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- migratePostgres(ctx, client.db) }()
+	}
+	for i := 0; i < cap(errs); i++ {
+		require.NoError(t, <-errs)
+	}
+
+	conn, err := client.db.Acquire(ctx)
+	require.NoError(t, err)
+	defer conn.Release()
+
+	var locked bool
+	require.NoError(t, conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", migrationLockID).Scan(&locked))
+	require.True(t, locked, "the lock is released after migrating")
+	_, err = conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID)
+	require.NoError(t, err)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/logging"
+	"go.uber.org/zap"
+)
+
+var nopLogger = zap.NewNop()
+
+// logQuery emits a debug line per statement through the request-scoped logger
+// in ctx, so query logs carry the same request id as the handler that issued them.
+func logQuery(ctx context.Context, query string, start time.Time, err error) {
+	logging.FromContext(ctx, nopLogger).Debug("database query",
+		zap.String("query", query),
+		zap.Duration("duration", time.Since(start)),
+		zap.Error(err),
+	)
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+var (
+	poolAcquiredConnsDesc = prometheus.NewDesc("db_pool_acquired_connections", "Number of connections currently acquired from the pool.", nil, nil)
+	poolIdleConnsDesc     = prometheus.NewDesc("db_pool_idle_connections", "Number of idle connections in the pool.", nil, nil)
+	poolTotalConnsDesc    = prometheus.NewDesc("db_pool_total_connections", "Total number of connections in the pool.", nil, nil)
+	poolMaxConnsDesc      = prometheus.NewDesc("db_pool_max_connections", "Maximum size of the pool.", nil, nil)
+	poolAcquiresDesc      = prometheus.NewDesc("db_pool_acquires_total", "Number of successful connection acquisitions.", nil, nil)
+	poolEmptyAcquiresDesc = prometheus.NewDesc("db_pool_empty_acquires_total", "Number of acquisitions that had to wait for a connection.", nil, nil)
+	poolAcquireWaitDesc   = prometheus.NewDesc("db_pool_acquire_wait_seconds_total", "Total time spent waiting to acquire connections.", nil, nil)
+)
+
+// poolCollector exports pgxpool statistics.
+type poolCollector struct {
+	client *Client
+}
+
+// Collector returns a Prometheus collector for the connection pool statistics.
+func (c *Client) Collector() prometheus.Collector {
+	return poolCollector{client: c}
+}
+
+func (p poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(p, ch)
+}
+
+func (p poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := p.client.db.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConnsDesc, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConnsDesc, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConnsDesc, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConnsDesc, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquiresDesc, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquiresDesc, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireWaitDesc, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
+
+// Collector returns a Prometheus collector for the database/sql connection statistics.
+func (c *SQLiteClient) Collector() prometheus.Collector {
+	return collectors.NewDBStatsCollector(c.db, "sqlite")
+}
@@ -0,0 +1,98 @@
+package repository
+
+import "context"
+
+// migration is one schema change. Statements must be valid for both
+// PostgreSQL and SQLite unless postgresOnly is set, in which case SQLite
+// records the version without running the statement.
+//
+// Migrations run outside transactions, so PostgreSQL indexes can be built
+// with CREATE INDEX CONCURRENTLY without blocking writes. Such a migration
+// names its index in concurrentIndex: a concurrent build that fails leaves an
+// invalid index behind, which is dropped before the build is retried.
+type migration struct {
+	statement       string
+	postgresOnly    bool
+	concurrentIndex string
+}
+
+// migrations holds the ordered schema changes shared by every storage backend;
+// a migration's version is its position in the slice, starting at 1.
+var migrations = []migration{
+	{statement: `CREATE TABLE IF NOT EXISTS devices (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		brand TEXT NOT NULL,
+		creation_time TIMESTAMPTZ NOT NULL,
+		update_time TIMESTAMPTZ NOT NULL
+	)`},
+	{statement: `CREATE INDEX IF NOT EXISTS idx_brand ON devices(brand)`},
+	// Rate limit state shared by replicas; only the PostgreSQL backend uses it.
+	{statement: `CREATE TABLE IF NOT EXISTS rate_limits (
+		key TEXT PRIMARY KEY,
+		tat TIMESTAMPTZ NOT NULL
+	)`},
+	// Full-text search over names, weighted above brands, with trigram
+	// matching of misspelled terms as a fallback.
+	{postgresOnly: true, statement: `CREATE EXTENSION IF NOT EXISTS pg_trgm`},
+	// Adding a stored generated column rewrites the whole table under an
+	// ACCESS EXCLUSIVE lock, blocking reads and writes for as long as that
+	// takes; on a large table, apply it in a maintenance window.
+	{postgresOnly: true, statement: `ALTER TABLE devices ADD COLUMN IF NOT EXISTS search tsvector
+		GENERATED ALWAYS AS (setweight(to_tsvector('simple', name), 'A') || setweight(to_tsvector('simple', brand), 'B')) STORED`},
+	{postgresOnly: true, concurrentIndex: "idx_search", statement: `CREATE INDEX CONCURRENTLY idx_search ON devices USING GIN (search)`},
+	{postgresOnly: true, concurrentIndex: "idx_search_trgm", statement: `CREATE INDEX CONCURRENTLY idx_search_trgm ON devices USING GIN ((name || ' ' || brand) gin_trgm_ops)`},
+}
+
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// migrator abstracts the database handle so both backends can share migrate.
+// The PostgreSQL one runs on a single connection holding an advisory lock, so
+// replicas starting together apply the migrations one at a time.
+type migrator interface {
+	exec(ctx context.Context, query string, args ...any) error
+	version(ctx context.Context) (int, error)
+	postgres() bool
+}
+
+// migrate applies every migration newer than the recorded schema version.
+func migrate(ctx context.Context, m migrator) error {
+	if err := m.exec(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+
+	current, err := m.version(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := current; i < len(migrations); i++ {
+		if !migrations[i].postgresOnly || m.postgres() {
+			if index := migrations[i].concurrentIndex; index != "" {
+				if err := m.exec(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+index); err != nil {
+					return err
+				}
+			}
+			if err := m.exec(ctx, migrations[i].statement); err != nil {
+				return err
+			}
+		}
+		if err := m.exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1) ON CONFLICT DO NOTHING", i+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+const selectSchemaVersion = "SELECT COALESCE(MAX(version), 0) FROM schema_migrations"
+
+// migrationStatus returns the applied schema version and the latest one this build knows.
+func migrationStatus(ctx context.Context, m migrator) (current int, latest int, err error) {
+	current, err = m.version(ctx)
+	return current, len(migrations), err
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMigrator records the statements of a PostgreSQL migration run.
+type recordingMigrator struct {
+	statements []string
+}
+
+func (m *recordingMigrator) exec(ctx context.Context, query string, args ...any) error {
+	m.statements = append(m.statements, strings.Join(strings.Fields(query), " "))
+	return nil
+}
+
+func (m *recordingMigrator) version(ctx context.Context) (int, error) { return 0, nil }
+
+func (m *recordingMigrator) postgres() bool { return true }
+
+func TestMigrate_RebuildsConcurrentIndexes(t *testing.T) {
+	m := &recordingMigrator{}
+	require.NoError(t, migrate(context.Background(), m))
+
+	for _, index := range []string{"idx_search", "idx_search_trgm"} {
+		drop := indexOf(m.statements, "DROP INDEX CONCURRENTLY IF EXISTS "+index)
+		create := indexOf(m.statements, "CREATE INDEX CONCURRENTLY "+index+" ")
+		require.NotEqual(t, -1, drop, index)
+		assert.Equal(t, drop+1, create, "an invalid index left by a failed build is dropped first")
+	}
+}
+
+func indexOf(statements []string, prefix string) int {
+	for i, statement := range statements {
+		if strings.HasPrefix(statement, prefix) {
+			return i
+		}
+	}
+	return -1
+}
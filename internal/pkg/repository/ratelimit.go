@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/logging"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/ratelimit"
+	"go.uber.org/zap"
+)
+
+// reserveRateLimit advances a key's theoretical arrival time in one atomic
+// upsert; the WHERE clause leaves the row untouched, and returns nothing,
+// when the request is over the limit.
+const reserveRateLimit = `
+INSERT INTO rate_limits AS r (key, tat) VALUES ($1, $2::timestamptz + $3::bigint * interval '1 microsecond')
+ON CONFLICT (key) DO UPDATE
+SET tat = GREATEST(r.tat, $2::timestamptz) + $3::bigint * interval '1 microsecond'
+WHERE GREATEST(r.tat, $2::timestamptz) + $3::bigint * interval '1 microsecond' <= $2::timestamptz + $4::bigint * interval '1 microsecond'
+RETURNING tat`
+
+// rateLimitSweepEvery is how often Sweep deletes expired rate limit rows.
+const rateLimitSweepEvery = time.Minute
+
+// RateLimitStore keeps rate limit state in PostgreSQL, so every replica
+// sharing the database enforces the same quotas.
+type RateLimitStore struct {
+	client *Client
+}
+
+// RateLimitStore returns a ratelimit.Store backed by the database.
+func (c *Client) RateLimitStore() *RateLimitStore {
+	return &RateLimitStore{client: c}
+}
+
+var _ ratelimit.Store = (*RateLimitStore)(nil)
+
+// Reserve implements ratelimit.Store.
+func (s *RateLimitStore) Reserve(ctx context.Context, key string, now time.Time, interval, period time.Duration) (time.Time, bool, error) {
+	now = now.UTC().Truncate(time.Microsecond)
+
+	var tat time.Time
+	err := s.client.db.QueryRow(ctx, reserveRateLimit, key, now, interval.Microseconds(), period.Microseconds()).Scan(&tat)
+	if err == nil {
+		return tat, true, nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, err
+	}
+
+	err = s.client.db.QueryRow(ctx, "SELECT GREATEST(tat, $2::timestamptz) FROM rate_limits WHERE key=$1", key, now).Scan(&tat)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The row expired and was swept in between; the request may go ahead next time.
+		return now, false, nil
+	} else if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return tat, false, nil
+}
+
+// Sweep deletes rows whose quota is full again, which are equivalent to
+// absent ones, every rateLimitSweepEvery until ctx is canceled. Failures are
+// logged through the logger in ctx and retried on the next tick.
+func (s *RateLimitStore) Sweep(ctx context.Context) error {
+	ticker := time.NewTicker(rateLimitSweepEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := s.client.db.Exec(ctx, "DELETE FROM rate_limits WHERE tat < $1", now()); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx, nopLogger).Warn("rate limit sweep failed", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteTimeLayout is a fixed-width UTC layout, so stored timestamps sort correctly as text.
+const sqliteTimeLayout = "2006-01-02 15:04:05.000000000Z07:00"
+
+// SQLiteClient connects to an embedded SQLite database and implements a repository interface.
+type SQLiteClient struct {
+	db *sql.DB
+	// conn runs the queries: the database, or the transaction of a client
+	// handed to a WithTx callback.
+	conn sqlConn
+	tx   bool
+}
+
+// sqlConn is the part of *sql.DB and *sql.Tx that queries use.
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// NewSQLite opens the SQLite database at path (":memory:" for a transient one) and applies migrations.
+func NewSQLite(path string) (*SQLiteClient, error) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer; serializing access through one connection
+	// avoids SQLITE_BUSY errors and keeps in-memory databases alive.
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(ctx, sqliteMigrator{db}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteClient{db: db, conn: db}, nil
+}
+
+// sqliteMigrator runs migrations on a database/sql handle.
+type sqliteMigrator struct {
+	db *sql.DB
+}
+
+func (m sqliteMigrator) exec(ctx context.Context, query string, args ...any) error {
+	_, err := m.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (m sqliteMigrator) postgres() bool { return false }
+
+func (m sqliteMigrator) version(ctx context.Context) (int, error) {
+	var v int
+	err := m.db.QueryRowContext(ctx, selectSchemaVersion).Scan(&v)
+	return v, err
+}
+
+// Close closes the database.
+func (c *SQLiteClient) Close() {
+	c.db.Close()
+}
+
+// Ping checks that the database is reachable.
+func (c *SQLiteClient) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// MigrationStatus returns the applied schema version and the latest one known.
+func (c *SQLiteClient) MigrationStatus(ctx context.Context) (current int, latest int, err error) {
+	return migrationStatus(ctx, sqliteMigrator{c.db})
+}
+
+// Store adds a new device.
+func (c *SQLiteClient) Store(ctx context.Context, device *device.Device) error {
+	device.ID = uuid.New().String()
+	device.CreationTime = now()
+	device.UpdateTime = device.CreationTime
+
+	const query = "INSERT INTO devices (id, name, brand, creation_time, update_time) VALUES ($1, $2, $3, $4, $5)"
+
+	start := time.Now()
+	_, err := c.conn.ExecContext(
+		ctx,
+		query,
+		device.ID, device.Name, device.Brand, formatSQLiteTime(device.CreationTime), formatSQLiteTime(device.UpdateTime),
+	)
+	logQuery(ctx, query, start, err)
+
+	return err
+}
+
+// FindByID gets a device by its ID.
+func (c *SQLiteClient) FindByID(ctx context.Context, id string) (*device.Device, error) {
+	const query = "SELECT id, name, brand, creation_time, update_time FROM devices WHERE id=$1"
+
+	start := time.Now()
+	row := c.conn.QueryRowContext(ctx, query, id)
+
+	device, err := scanSQLiteDevice(row)
+	logQuery(ctx, query, start, err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+// FindByIDs gets the devices with the given IDs in a single query.
+func (c *SQLiteClient) FindByIDs(ctx context.Context, ids []string) ([]device.Device, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders, args := inList(ids)
+	return c.query(ctx, "SELECT id, name, brand, creation_time, update_time FROM devices WHERE id IN ("+placeholders+") ORDER BY creation_time, id", args...)
+}
+
+// RemoveByIDs deletes the devices with the given IDs in a single statement.
+func (c *SQLiteClient) RemoveByIDs(ctx context.Context, ids []string) ([]device.Device, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders, args := inList(ids)
+	devices, err := c.query(ctx, "DELETE FROM devices WHERE id IN ("+placeholders+") RETURNING id, name, brand, creation_time, update_time", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite cannot order RETURNING rows.
+	device.SortDevices(devices)
+	return devices, nil
+}
+
+// List gets all devices.
+func (c *SQLiteClient) List(ctx context.Context) ([]device.Device, error) {
+	return c.query(ctx, "SELECT id, name, brand, creation_time, update_time FROM devices ORDER BY creation_time, id")
+}
+
+// ListPage gets one page of devices matching options.
+func (c *SQLiteClient) ListPage(ctx context.Context, options device.ListOptions) (device.Page, error) {
+	query, args, err := listPageStatement(options, func(t time.Time) any { return formatSQLiteTime(t) })
+	if err != nil {
+		return device.Page{}, err
+	}
+
+	devices, err := c.query(ctx, query, args...)
+	if err != nil {
+		return device.Page{}, err
+	}
+
+	return device.NewPage(devices, options.PageSize), nil
+}
+
+// Update updates a device.
+func (c *SQLiteClient) Update(ctx context.Context, device *device.Device) error {
+	query, args, err := updateStatement(device, formatSQLiteTime(now()))
+	if err != nil {
+		return err
+	}
+
+	return c.exec(ctx, query, args...)
+}
+
+// Remove deletes a device by its ID.
+func (c *SQLiteClient) Remove(ctx context.Context, id string) error {
+	return c.exec(ctx, "DELETE FROM devices WHERE id=$1", id)
+}
+
+// FindByBrand gets a list of devices by brand.
+func (c *SQLiteClient) FindByBrand(ctx context.Context, brand string) ([]device.Device, error) {
+	return c.query(ctx, "SELECT id, name, brand, creation_time, update_time FROM devices WHERE brand=$1 ORDER BY creation_time, id", brand)
+}
+
+// CountByBrand gets the number of devices per brand, of the given brands only
+// when any are passed.
+func (c *SQLiteClient) CountByBrand(ctx context.Context, brands ...string) (map[string]int, error) {
+	query, args := "SELECT brand, COUNT(*) FROM devices GROUP BY brand", []any(nil)
+	if len(brands) > 0 {
+		var placeholders string
+		placeholders, args = inList(brands)
+		query = "SELECT brand, COUNT(*) FROM devices WHERE brand IN (" + placeholders + ") GROUP BY brand"
+	}
+
+	start := time.Now()
+	rows, err := c.conn.QueryContext(ctx, query, args...)
+	logQuery(ctx, query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var (
+			brand string
+			count int
+		)
+		if err := rows.Scan(&brand, &count); err != nil {
+			return nil, err
+		}
+		counts[brand] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// Search ranks every device in memory with device.SearchDevices; SQLite has
+// no trigram index to push fuzzy matching to.
+func (c *SQLiteClient) Search(ctx context.Context, query string, limit int) ([]device.SearchResult, error) {
+	if len(device.SearchTerms(query)) == 0 {
+		return nil, nil
+	}
+
+	devices, err := c.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return device.SearchDevices(devices, query, limit), nil
+}
+
+// sqliteBuckets maps each interval to the SQL expression of the date, as
+// YYYY-MM-DD, its buckets start on.
+var sqliteBuckets = map[device.Interval]string{
+	device.Day:   "substr(creation_time, 1, 10)",
+	device.Week:  "date(substr(creation_time, 1, 10), '-6 days', 'weekday 1')",
+	device.Month: "substr(creation_time, 1, 7) || '-01'",
+}
+
+// CreationHistogram counts the devices created per interval. Stored times are
+// UTC, so their date prefix is the UTC day.
+func (c *SQLiteClient) CreationHistogram(ctx context.Context, options device.HistogramOptions) ([]device.Bucket, error) {
+	bucket, found := sqliteBuckets[options.Interval]
+	if !found {
+		return nil, device.ErrInvalidInterval
+	}
+	query := "SELECT " + bucket + ", COUNT(*) FROM devices WHERE creation_time >= $1 AND creation_time < $2 GROUP BY 1 ORDER BY 1"
+
+	start := time.Now()
+	rows, err := c.conn.QueryContext(ctx, query, formatSQLiteTime(options.From), formatSQLiteTime(options.To))
+	logQuery(ctx, query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []device.Bucket
+	for rows.Next() {
+		var (
+			day   string
+			count int
+		)
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.DateOnly, day)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, device.Bucket{Start: t, Count: count})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return device.FillBuckets(buckets, options), nil
+}
+
+// exec runs a statement that must affect at least one row.
+func (c *SQLiteClient) exec(ctx context.Context, query string, args ...any) error {
+	start := time.Now()
+	result, err := c.conn.ExecContext(ctx, query, args...)
+	logQuery(ctx, query, start, err)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(rows)
+}
+
+func (c *SQLiteClient) query(ctx context.Context, query string, args ...any) ([]device.Device, error) {
+	start := time.Now()
+	rows, err := c.conn.QueryContext(ctx, query, args...)
+	logQuery(ctx, query, start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []device.Device
+	for rows.Next() {
+		device, err := scanSQLiteDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *device)
+	}
+
+	return devices, rows.Err()
+}
+
+// inList returns the placeholders and arguments binding ids in an IN list.
+func inList(ids []string) (string, []any) {
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+// scanSQLiteDevice reads a device row, parsing the text-encoded timestamps.
+func scanSQLiteDevice(row interface{ Scan(...any) error }) (*device.Device, error) {
+	var (
+		device       device.Device
+		creationTime string
+		updateTime   string
+	)
+
+	if err := row.Scan(&device.ID, &device.Name, &device.Brand, &creationTime, &updateTime); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if device.CreationTime, err = time.Parse(sqliteTimeLayout, creationTime); err != nil {
+		return nil, err
+	}
+	if device.UpdateTime, err = time.Parse(sqliteTimeLayout, updateTime); err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+func formatSQLiteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device/devicetest"
+)
+
+func newSQLiteClient(t *testing.T) *SQLiteClient {
+	t.Helper()
+	client, err := NewSQLite(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestSQLiteClient_Conformance(t *testing.T) {
+	devicetest.RepositoryConformance(t, func(t *testing.T) device.Repository {
+		return newSQLiteClient(t)
+	})
+}
+
+func TestSQLiteClient_MigrationsAreIdempotent(t *testing.T) {
+	client := newSQLiteClient(t)
+
+	m := sqliteMigrator{client.db}
+	require.NoError(t, migrate(context.Background(), m))
+
+	current, latest, err := client.MigrationStatus(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, len(migrations), current)
+	assert.Equal(t, len(migrations), latest)
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer is a pgx.QueryTracer starting a client span per query, as a
+// child of the span carried by the query context, and logging the query.
+type queryTracer struct{}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql  string
+	time time.Time
+}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := "QUERY"
+	if fields := strings.Fields(data.SQL); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	ctx, _ = tracing.Tracer().Start(ctx, "postgres "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, time: time.Now()})
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	if start, ok := ctx.Value(queryStartKey{}).(queryStart); ok {
+		logQuery(ctx, start.sql, start.time, data.Err)
+	}
+
+	if data.Err != nil && !errors.Is(data.Err, pgx.ErrNoRows) {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+		return
+	}
+
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
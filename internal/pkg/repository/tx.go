@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	pgxv5 "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+)
+
+const (
+	// maxTxAttempts bounds how many times WithTx runs a transaction that
+	// keeps failing on serialization conflicts.
+	maxTxAttempts = 5
+	// txRetryDelay is the base of the jittered, doubling delay between attempts.
+	txRetryDelay = 5 * time.Millisecond
+)
+
+// WithTx runs fn in a PostgreSQL transaction at the requested isolation
+// level, retrying it from the start on serialization failures and deadlocks.
+func (c *Client) WithTx(ctx context.Context, options device.TxOptions, fn func(tx device.Repository) error) error {
+	// The level goes into BEGIN as it is.
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	if c.tx {
+		return fn(c)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := c.runTx(ctx, options, fn)
+		if err == nil || attempt == maxTxAttempts || !retryableTxError(err) {
+			return err
+		}
+
+		delay := txRetryDelay << (attempt - 1)
+		select {
+		case <-time.After(delay/2 + rand.N(delay/2)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (c *Client) runTx(ctx context.Context, options device.TxOptions, fn func(tx device.Repository) error) error {
+	tx, err := c.db.BeginTx(ctx, pgxv5.TxOptions{IsoLevel: pgxv5.TxIsoLevel(options.Isolation)})
+	if err != nil {
+		return err
+	}
+	// Rolling back a committed transaction is a no-op; this covers errors and panics.
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	if err := fn(&Client{db: c.db, conn: tx, tx: true}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// retryableTxError reports whether err aborted a transaction that may
+// succeed when run again: serialization_failure or deadlock_detected.
+func retryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
+
+// WithTx runs fn in a SQLite transaction. SQLite transactions are always
+// serializable and, with a single connection, never conflict, so the
+// isolation level is only validated and nothing is retried. fn must only use
+// tx: the connection is held by the transaction until fn returns.
+func (c *SQLiteClient) WithTx(ctx context.Context, options device.TxOptions, fn func(tx device.Repository) error) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	if c.tx {
+		return fn(c)
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&SQLiteClient{db: c.db, conn: tx, tx: true}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
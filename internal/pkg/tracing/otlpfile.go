@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// fileClient is an otlptrace.Client that appends each export request to a
+// file as a line of OTLP/JSON, the format read by the collector's
+// otlpjsonfile receiver.
+type fileClient struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+func (c *fileClient) Start(ctx context.Context) error {
+	file, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.file = file
+
+	return nil
+}
+
+func (c *fileClient) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.file.Close()
+}
+
+func (c *fileClient) UploadTraces(ctx context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	line, err := marshalOTLPJSON(&coltracepb.ExportTraceServiceRequest{ResourceSpans: protoSpans})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err = c.file.Write(append(line, '\n'))
+	return err
+}
+
+// marshalOTLPJSON encodes request as OTLP/JSON. It differs from plain protojson
+// in that trace and span ids are hex strings rather than base64.
+func marshalOTLPJSON(request *coltracepb.ExportTraceServiceRequest) ([]byte, error) {
+	encoded, err := protojson.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		return nil, err
+	}
+
+	for _, resourceSpans := range objects(doc["resourceSpans"]) {
+		for _, scopeSpans := range objects(resourceSpans["scopeSpans"]) {
+			for _, span := range objects(scopeSpans["spans"]) {
+				hexEncodeIDs(span)
+				for _, link := range objects(span["links"]) {
+					hexEncodeIDs(link)
+				}
+			}
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+func objects(v any) []map[string]any {
+	list, _ := v.([]any)
+	result := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if object, ok := item.(map[string]any); ok {
+			result = append(result, object)
+		}
+	}
+	return result
+}
+
+func hexEncodeIDs(object map[string]any) {
+	for _, key := range []string{"traceId", "spanId", "parentSpanId"} {
+		value, ok := object[key].(string)
+		if !ok {
+			continue
+		}
+		if raw, err := base64.StdEncoding.DecodeString(value); err == nil {
+			object[key] = hex.EncodeToString(raw)
+		}
+	}
+}
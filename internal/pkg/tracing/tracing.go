@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	serviceName = "devices-service"
+	tracerName  = "github.com/victorspringer/1g-take-home-task"
+)
+
+// Supported exporter names.
+const (
+	ExporterNone     = "none"
+	ExporterStdout   = "stdout"
+	ExporterOTLPFile = "otlpfile"
+)
+
+// Setup installs the global tracer provider and W3C trace context propagator.
+//
+// exporter selects where spans go: ExporterNone keeps propagation working
+// without recording spans, ExporterStdout prints them as JSON and
+// ExporterOTLPFile appends OTLP/JSON lines to path. The returned function
+// flushes pending spans and must be called before the process exits.
+func Setup(ctx context.Context, exporter, path string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		spanExporter sdktrace.SpanExporter
+		err          error
+	)
+
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLPFile:
+		spanExporter, err = otlptrace.New(ctx, &fileClient{path: path})
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the service tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// LogFields returns zap fields correlating a log line with the span in ctx, if any.
+func LogFields(ctx context.Context) []zap.Field {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+
+	return []zap.Field{
+		zap.String("traceId", spanContext.TraceID().String()),
+		zap.String("spanId", spanContext.SpanID().String()),
+	}
+}
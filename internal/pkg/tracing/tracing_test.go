@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetup_OTLPFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+	shutdown, err := Setup(context.Background(), ExporterOTLPFile, path)
+	require.NoError(t, err)
+
+	_, span := Tracer().Start(context.Background(), "test span")
+	span.End()
+
+	require.NoError(t, shutdown(context.Background()))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := bytes.Split(bytes.TrimSpace(content), []byte("\n"))
+	require.Len(t, lines, 1)
+
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					Name    string `json:"name"`
+					TraceID string `json:"traceId"`
+					SpanID  string `json:"spanId"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	require.NoError(t, json.Unmarshal(lines[0], &request))
+	require.Len(t, request.ResourceSpans, 1)
+
+	exported := request.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "test span", exported.Name)
+	assert.Equal(t, span.SpanContext().TraceID().String(), exported.TraceID)
+	assert.Equal(t, span.SpanContext().SpanID().String(), exported.SpanID)
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), "jaeger", "")
+	assert.EqualError(t, err, `unsupported tracing exporter "jaeger"`)
+}
+
+func TestLogFields(t *testing.T) {
+	assert.Empty(t, LogFields(context.Background()))
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	fields := LogFields(ctx)
+	require.Len(t, fields, 2)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", fields[0].String)
+	assert.Equal(t, "00f067aa0ba902b7", fields[1].String)
+}
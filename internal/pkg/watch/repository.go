@@ -0,0 +1,231 @@
+// Package watch publishes device changes to subscribers.
+package watch
+
+import (
+	"context"
+	"sync"
+
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+)
+
+// EventType tells what happened to a device.
+type EventType string
+
+const (
+	Created EventType = "created"
+	Updated EventType = "updated"
+	Deleted EventType = "deleted"
+)
+
+// Event is a change to a device. Updated events carry the device as stored
+// after the change; Deleted events the device as it was before removal.
+type Event struct {
+	Type   EventType
+	Device device.Device
+}
+
+// Repository is a device.Repository decorator that publishes an Event for
+// every successful write made through it.
+//
+// Only writes made through this instance are seen: replicas sharing the
+// database each publish their own writes.
+type Repository struct {
+	next device.Repository
+	// root holds the subscribers; the transaction-scoped copies WithTx hands
+	// out point back at the repository they were made from.
+	root *Repository
+	// pending collects the events of a transaction until it commits.
+	pending *[]Event
+
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// New wraps next, publishing its writes.
+func New(next device.Repository) *Repository {
+	r := &Repository{
+		next:        next,
+		subscribers: make(map[chan Event]struct{}),
+	}
+	r.root = r
+	return r
+}
+
+// Subscribe returns a channel receiving every event published after the call,
+// in order. The channel is closed when ctx is done, or early if the
+// subscriber falls more than buffer events behind, so a slow subscriber never
+// blocks writers; it must then resubscribe and reconcile with a listing.
+func (r *Repository) Subscribe(ctx context.Context, buffer int) <-chan Event {
+	events := make(chan Event, buffer)
+
+	r.mu.Lock()
+	r.subscribers[events] = struct{}{}
+	r.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.unsubscribe(events)
+	}()
+
+	return events
+}
+
+// unsubscribe closes events unless it was already dropped. r.mu must be held.
+func (r *Repository) unsubscribe(events chan Event) {
+	if _, found := r.subscribers[events]; found {
+		delete(r.subscribers, events)
+		close(events)
+	}
+}
+
+// watched reports whether anyone is subscribed, so writes only pay for
+// loading the full device of their events when it is delivered.
+func (r *Repository) watched() bool {
+	r.root.mu.Lock()
+	defer r.root.mu.Unlock()
+	return len(r.root.subscribers) > 0
+}
+
+func (r *Repository) publish(event Event) {
+	if r.pending != nil {
+		*r.pending = append(*r.pending, event)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for events := range r.subscribers {
+		select {
+		case events <- event:
+		default:
+			r.unsubscribe(events)
+		}
+	}
+}
+
+// Store adds a new device and publishes a Created event.
+func (r *Repository) Store(ctx context.Context, dvc *device.Device) error {
+	if err := r.next.Store(ctx, dvc); err != nil {
+		return err
+	}
+
+	r.publish(Event{Type: Created, Device: *dvc})
+	return nil
+}
+
+// WithTx runs fn in a transaction of the wrapped repository and publishes the
+// events of the writes made through tx once the transaction commits.
+func (r *Repository) WithTx(ctx context.Context, options device.TxOptions, fn func(tx device.Repository) error) error {
+	transactor, ok := r.next.(device.Transactor)
+	if !ok {
+		return device.ErrTxUnsupported
+	}
+
+	var events []Event
+	err := transactor.WithTx(ctx, options, func(tx device.Repository) error {
+		// The backend may run fn again after a conflict.
+		events = events[:0]
+		return fn(&Repository{next: tx, root: r.root, pending: &events})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		r.publish(event)
+	}
+	return nil
+}
+
+// FindByID gets a device by its ID.
+func (r *Repository) FindByID(ctx context.Context, id string) (*device.Device, error) {
+	return r.next.FindByID(ctx, id)
+}
+
+// FindByIDs gets the devices with the given IDs.
+func (r *Repository) FindByIDs(ctx context.Context, ids []string) ([]device.Device, error) {
+	return r.next.FindByIDs(ctx, ids)
+}
+
+// List gets all devices.
+func (r *Repository) List(ctx context.Context) ([]device.Device, error) {
+	return r.next.List(ctx)
+}
+
+// ListPage gets one page of devices.
+func (r *Repository) ListPage(ctx context.Context, options device.ListOptions) (device.Page, error) {
+	return r.next.ListPage(ctx, options)
+}
+
+// Update updates a device and publishes an Updated event with its new state,
+// loaded back from the wrapped repository while anyone is subscribed.
+func (r *Repository) Update(ctx context.Context, dvc *device.Device) error {
+	if err := r.next.Update(ctx, dvc); err != nil {
+		return err
+	}
+
+	updated := *dvc
+	if r.watched() {
+		if found, err := r.next.FindByID(ctx, dvc.ID); err == nil && found != nil {
+			updated = *found
+		}
+	}
+	r.publish(Event{Type: Updated, Device: updated})
+	return nil
+}
+
+// Remove deletes a device by its ID and publishes a Deleted event, with the
+// device as it was loaded before removal while anyone is subscribed.
+func (r *Repository) Remove(ctx context.Context, id string) error {
+	removed := device.Device{ID: id}
+	if r.watched() {
+		if found, err := r.next.FindByID(ctx, id); err == nil && found != nil {
+			removed = *found
+		}
+	}
+
+	if err := r.next.Remove(ctx, id); err != nil {
+		return err
+	}
+
+	r.publish(Event{Type: Deleted, Device: removed})
+	return nil
+}
+
+// RemoveByIDs deletes the devices with the given IDs and publishes a Deleted
+// event for each of them.
+func (r *Repository) RemoveByIDs(ctx context.Context, ids []string) ([]device.Device, error) {
+	removed, err := r.next.RemoveByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dvc := range removed {
+		r.publish(Event{Type: Deleted, Device: dvc})
+	}
+	return removed, nil
+}
+
+// FindByBrand gets a list of devices by brand.
+func (r *Repository) FindByBrand(ctx context.Context, brand string) ([]device.Device, error) {
+	return r.next.FindByBrand(ctx, brand)
+}
+
+// CountByBrand gets the number of devices per brand, of the given brands only
+// when any are passed.
+func (r *Repository) CountByBrand(ctx context.Context, brands ...string) (map[string]int, error) {
+	return r.next.CountByBrand(ctx, brands...)
+}
+
+// Search finds devices matching query.
+func (r *Repository) Search(ctx context.Context, query string, limit int) ([]device.SearchResult, error) {
+	return r.next.Search(ctx, query, limit)
+}
+
+// CreationHistogram counts the devices created per interval.
+func (r *Repository) CreationHistogram(ctx context.Context, options device.HistogramOptions) ([]device.Bucket, error) {
+	return r.next.CreationHistogram(ctx, options)
+}
@@ -0,0 +1,160 @@
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device"
+	"github.com/victorspringer/1g-take-home-task/internal/pkg/device/devicetest"
+)
+
+func TestRepository_Conformance(t *testing.T) {
+	devicetest.RepositoryConformance(t, func(t *testing.T) device.Repository {
+		return New(&device.MockRepository{})
+	})
+}
+
+func receive(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "subscription closed")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func TestRepository_PublishesWrites(t *testing.T) {
+	ctx := context.Background()
+	repo := New(&device.MockRepository{})
+	events := repo.Subscribe(ctx, 10)
+
+	dvc := &device.Device{Name: "Pixel 8", Brand: "Google"}
+	require.NoError(t, repo.Store(ctx, dvc))
+	require.NoError(t, repo.Update(ctx, &device.Device{ID: dvc.ID, Name: "Pixel 8 Pro"}))
+	require.NoError(t, repo.Remove(ctx, dvc.ID))
+
+	created := receive(t, events)
+	assert.Equal(t, Created, created.Type)
+	assert.Equal(t, *dvc, created.Device)
+
+	updated := receive(t, events)
+	assert.Equal(t, Updated, updated.Type)
+	assert.Equal(t, "Pixel 8 Pro", updated.Device.Name)
+	assert.Equal(t, "Google", updated.Device.Brand, "updated events carry the whole device")
+
+	deleted := receive(t, events)
+	assert.Equal(t, Deleted, deleted.Type)
+	assert.Equal(t, dvc.ID, deleted.Device.ID)
+	assert.Equal(t, "Google", deleted.Device.Brand)
+}
+
+// countingRepository counts the lookups by id made through it.
+type countingRepository struct {
+	device.MockRepository
+	lookups int
+}
+
+func (r *countingRepository) FindByID(ctx context.Context, id string) (*device.Device, error) {
+	r.lookups++
+	return r.MockRepository.FindByID(ctx, id)
+}
+
+func TestRepository_LoadsDevicesOnlyWhenWatched(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingRepository{}
+	repo := New(backend)
+
+	dvc := &device.Device{Name: "Pixel 8", Brand: "Google"}
+	require.NoError(t, repo.Store(ctx, dvc))
+	require.NoError(t, repo.Update(ctx, &device.Device{ID: dvc.ID, Name: "Pixel 8 Pro"}))
+	require.NoError(t, repo.Remove(ctx, dvc.ID))
+	assert.Zero(t, backend.lookups, "writes nobody watches make no extra lookups")
+
+	events := repo.Subscribe(ctx, 10)
+	dvc = &device.Device{Name: "Pixel 9", Brand: "Google"}
+	require.NoError(t, repo.Store(ctx, dvc))
+	require.NoError(t, repo.Update(ctx, &device.Device{ID: dvc.ID, Name: "Pixel 9 Pro"}))
+	assert.Equal(t, 1, backend.lookups)
+	receive(t, events)
+	assert.Equal(t, "Google", receive(t, events).Device.Brand)
+
+	err := repo.WithTx(ctx, device.TxOptions{}, func(tx device.Repository) error {
+		return tx.Update(ctx, &device.Device{ID: dvc.ID, Name: "Pixel 9a"})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Google", receive(t, events).Device.Brand, "transactions see the subscribers too")
+}
+
+func TestRepository_FailedWritesAreNotPublished(t *testing.T) {
+	ctx := context.Background()
+	repo := New(&device.MockRepository{})
+	events := repo.Subscribe(ctx, 10)
+
+	assert.ErrorIs(t, repo.Remove(ctx, "missing"), device.ErrNotFound)
+	assert.ErrorIs(t, repo.Update(ctx, &device.Device{ID: "missing", Name: "Pixel 9"}), device.ErrNotFound)
+
+	assert.Empty(t, events)
+}
+
+func TestRepository_SubscriptionEndsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	repo := New(&device.MockRepository{})
+	events := repo.Subscribe(ctx, 1)
+
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("subscription not closed")
+	}
+}
+
+func TestRepository_DropsSlowSubscribers(t *testing.T) {
+	ctx := context.Background()
+	repo := New(&device.MockRepository{})
+	slow := repo.Subscribe(ctx, 1)
+	fast := repo.Subscribe(ctx, 10)
+
+	require.NoError(t, repo.Store(ctx, &device.Device{Name: "Pixel 8", Brand: "Google"}))
+	require.NoError(t, repo.Store(ctx, &device.Device{Name: "Pixel 9", Brand: "Google"}))
+
+	assert.Equal(t, Created, receive(t, slow).Type)
+	_, ok := <-slow
+	assert.False(t, ok, "the subscriber that fell behind is dropped")
+
+	assert.Equal(t, "Pixel 8", receive(t, fast).Device.Name)
+	assert.Equal(t, "Pixel 9", receive(t, fast).Device.Name)
+}
+
+func TestRepository_PublishesTransactionsOnCommit(t *testing.T) {
+	ctx := context.Background()
+	repo := New(&device.MockRepository{})
+	events := repo.Subscribe(ctx, 10)
+
+	err := repo.WithTx(ctx, device.TxOptions{}, func(tx device.Repository) error {
+		if err := tx.Store(ctx, &device.Device{Name: "Pixel 8", Brand: "Google"}); err != nil {
+			return err
+		}
+		assert.Empty(t, events, "nothing is published before the commit")
+		return tx.Store(ctx, &device.Device{Name: "Pixel 9", Brand: "Google"})
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Pixel 8", receive(t, events).Device.Name)
+	assert.Equal(t, "Pixel 9", receive(t, events).Device.Name)
+
+	err = repo.WithTx(ctx, device.TxOptions{}, func(tx device.Repository) error {
+		require.NoError(t, tx.Store(ctx, &device.Device{Name: "iPhone 15", Brand: "Apple"}))
+		return device.ErrInvalidUpdate
+	})
+	assert.ErrorIs(t, err, device.ErrInvalidUpdate)
+	assert.Empty(t, events, "rolled back writes are not published")
+}